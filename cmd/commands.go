@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"nasa-apod-app/internal/app"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/migration"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const dateLayout = "2006-01-02"
+
+// runFunc runs a command with the loaded configuration and the positional
+// arguments left after the flags.
+type runFunc func(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	// setup registers the flags of the command and returns its run function.
+	setup func(flags *flag.FlagSet) runFunc
+}
+
+var commands = []command{
+	{name: "serve", summary: "serve the API and run the fetch worker (default)", setup: serveCommand},
+	{name: "fetch", summary: "fetch and store a single APOD", setup: fetchCommand},
+	{name: "backfill", summary: "fetch and store every missing APOD of a date range", setup: backfillCommand},
+	{name: "migrate", args: "up|down|status|redo|version", summary: "apply, roll back or list database migrations", setup: migrateCommand},
+	{name: "verify", summary: "check stored files against their recorded sizes and checksums, exits 3 on problems", setup: verifyCommand},
+	{name: "export", summary: "write stored entries as JSON lines", setup: exportCommand},
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// withApp builds the application for commands that work on stored data and
+// closes it afterwards.
+func withApp(cfg *config.Config, logger *zap.Logger, fn func(a *app.App) error) error {
+	a, err := app.NewApp(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	err = fn(a)
+	return errors.Join(err, a.Close())
+}
+
+func serveCommand(flags *flag.FlagSet) runFunc {
+	return func(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("serve takes no arguments")
+		}
+
+		a, err := app.NewApp(cfg, logger)
+		if err != nil {
+			return err
+		}
+		return a.Run()
+	}
+}
+
+func fetchCommand(flags *flag.FlagSet) runFunc {
+	date := flags.String("date", "", "date (YYYY-MM-DD) to fetch, defaults to the latest APOD")
+
+	return func(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+		if *date != "" {
+			if _, err := time.Parse(dateLayout, *date); err != nil {
+				return usageErrorf("invalid -date %q, use YYYY-MM-DD", *date)
+			}
+		}
+
+		return withApp(cfg, logger, func(a *app.App) error {
+			err := a.Fetch(ctx, *date)
+			if errors.Is(err, domain.ErrAlreadyExists) {
+				logger.Info("APOD is already stored", zap.String("date", *date))
+				return nil
+			}
+			return err
+		})
+	}
+}
+
+func backfillCommand(flags *flag.FlagSet) runFunc {
+	from := flags.String("from", "", "first date (YYYY-MM-DD) of the backfill, required")
+	to := flags.String("to", "", "last date (YYYY-MM-DD) of the backfill, defaults to today")
+
+	return func(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+		startDate, endDate, err := parseDateRange(*from, *to)
+		if err != nil {
+			return err
+		}
+		if startDate.IsZero() {
+			return usageErrorf("-from is required")
+		}
+
+		return withApp(cfg, logger, func(a *app.App) error {
+			return a.Backfill(ctx, startDate, endDate)
+		})
+	}
+}
+
+func migrateCommand(flags *flag.FlagSet) runFunc {
+	return func(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+		if len(args) != 1 {
+			return usageErrorf("migrate needs exactly one of up, down, status, redo or version")
+		}
+
+		switch args[0] {
+		case app.MigrateUp, app.MigrateDown, app.MigrateStatus, app.MigrateRedo, app.MigrateVersion:
+		default:
+			return usageErrorf("unknown migrate command %q, use up, down, status, redo or version", args[0])
+		}
+
+		version, statuses, err := app.Migrate(ctx, cfg, args[0], logger)
+		if err != nil {
+			return err
+		}
+
+		if args[0] == app.MigrateVersion {
+			_, err := fmt.Fprintln(os.Stdout, version)
+			return err
+		}
+		return writeMigrationStatus(os.Stdout, statuses)
+	}
+}
+
+func writeMigrationStatus(w io.Writer, statuses []migration.Status) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "VERSION\tMIGRATION\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.Applied {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(table, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return table.Flush()
+}
+
+func verifyCommand(flags *flag.FlagSet) runFunc {
+	return func(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+		return withApp(cfg, logger, func(a *app.App) error {
+			report, err := a.Verify(ctx)
+			if err != nil {
+				return err
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				return err
+			}
+
+			if len(report.Issues) > 0 {
+				return fmt.Errorf("%d stored files are missing or modified: %w", len(report.Issues), errIssuesFound)
+			}
+			return nil
+		})
+	}
+}
+
+func exportCommand(flags *flag.FlagSet) runFunc {
+	from := flags.String("from", "", "first date (YYYY-MM-DD) to export")
+	to := flags.String("to", "", "last date (YYYY-MM-DD) to export")
+	output := flags.String("output", "-", "file to write to, - for stdout")
+
+	return func(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+		if _, _, err := parseDateRange(*from, *to); err != nil {
+			return err
+		}
+
+		return withApp(cfg, logger, func(a *app.App) error {
+			if *output == "-" {
+				_, err := a.Export(ctx, os.Stdout, *from, *to)
+				return err
+			}
+
+			file, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+
+			_, err = a.Export(ctx, file, *from, *to)
+			return errors.Join(err, file.Close())
+		})
+	}
+}
+
+// parseDateRange parses the optional -from and -to flags, zero times stand for
+// flags that are not set.
+func parseDateRange(from, to string) (startDate, endDate time.Time, err error) {
+	if from != "" {
+		if startDate, err = time.Parse(dateLayout, from); err != nil {
+			return startDate, endDate, usageErrorf("invalid -from %q, use YYYY-MM-DD", from)
+		}
+	}
+	if to != "" {
+		if endDate, err = time.Parse(dateLayout, to); err != nil {
+			return startDate, endDate, usageErrorf("invalid -to %q, use YYYY-MM-DD", to)
+		}
+	}
+	if !startDate.IsZero() && !endDate.IsZero() && endDate.Before(startDate) {
+		return startDate, endDate, usageErrorf("-to is before -from")
+	}
+	return startDate, endDate, nil
+}
@@ -1,45 +1,148 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"log"
-	"nasa-apod-app/internal/app"
 	"nasa-apod-app/internal/config"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
+	_ "time/tzdata"
+)
+
+// Exit codes, so cron jobs and CI can tell a failed run from a bad invocation.
+const (
+	exitOK          = 0
+	exitFailure     = 1
+	exitUsage       = 2
+	exitIssuesFound = 3
+	exitInterrupted = 130
 )
 
 func main() {
-	envFilePath := flag.String("env", ".env", "path to .env file")
-	flag.Parse()
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		printUsage()
+		return exitOK
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage()
+		return exitUsage
+	}
+
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: apod %s [flags] %s\n\n%s\n\nFlags:\n", cmd.name, cmd.args, cmd.summary)
+		flags.PrintDefaults()
+	}
+	envFilePath := flags.String("env", ".env", "path to .env file")
+	printConfig := flags.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	configLoader := config.NewLoader(flags)
+	execute := cmd.setup(flags)
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
 
 	err := godotenv.Load(*envFilePath)
 	if err != nil {
 		log.Printf("launching without .env file: %v", err)
 	}
 
-	appConfig, err := config.ParseConfigFromEnv()
+	appConfig, err := configLoader.Load()
 	if err != nil {
-		log.Panic(err)
+		log.Print(err)
+		return exitUsage
+	}
+
+	if *printConfig {
+		if err := config.Write(os.Stdout, appConfig); err != nil {
+			log.Print(err)
+			return exitFailure
+		}
+		return exitOK
 	}
 
 	logger, err := initLogger()
 	if err != nil {
-		log.Panicf("Failed to initialize logger: %v", err)
+		log.Printf("Failed to initialize logger: %v", err)
+		return exitFailure
 	}
 	defer logger.Sync()
+	logger = config.RedactLogger(logger, appConfig)
 
-	a, err := app.NewApp(appConfig, logger)
-	if err != nil {
-		log.Panic(err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = execute(ctx, appConfig, logger, flags.Args())
+	code := exitCode(err)
+	switch code {
+	case exitOK:
+	case exitUsage:
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		flags.Usage()
+	default:
+		logger.Error("Command failed", zap.String("command", cmd.name), zap.Int("exit_code", code), zap.Error(err))
 	}
+	return code
+}
+
+// usageError marks errors caused by invalid arguments.
+type usageError struct {
+	error
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return usageError{fmt.Errorf(format, args...)}
+}
 
-	if err := a.Run(); err != nil {
-		log.Panic(err)
+// errIssuesFound is returned by commands that ran fine but found problems.
+var errIssuesFound = errors.New("problems found")
+
+func exitCode(err error) int {
+	var usageErr usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, errIssuesFound):
+		return exitIssuesFound
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	default:
+		return exitFailure
 	}
+}
 
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: apod <command> [flags]\n\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nWithout a command apod serves. Run apod <command> -h for the flags of a command.")
 }
 
 func initLogger() (*zap.Logger, error) {
@@ -1,41 +1,102 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	"go.uber.org/zap"
+	"io"
 	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/domain"
 	"nasa-apod-app/internal/handler"
+	"nasa-apod-app/internal/metrics"
 	"nasa-apod-app/internal/migration"
 	"nasa-apod-app/internal/repository"
 	"nasa-apod-app/internal/server"
 	"nasa-apod-app/internal/service"
+	"nasa-apod-app/internal/storage"
+	"nasa-apod-app/internal/storage/local"
+	"nasa-apod-app/internal/storage/s3"
+	"nasa-apod-app/internal/tracing"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 type App struct {
-	server *server.Server
+	config          *config.Config
+	migrator        *migration.Migratory
+	repo            repository.Repository
+	storage         storage.Storage
+	service         *service.ApodImagesService
+	worker          *service.APODWorker
+	shutdownTracing func(context.Context) error
+	logger          *zap.Logger
 }
 
-func NewApp(config *config.Config, logger *zap.Logger) (*App, error) {
-	migrator := migration.NewMigration()
+// NewApp connects to the database and storage and wires the services every
+// command shares. Nothing runs in the background until Run is called.
+func NewApp(config *config.Config, logger *zap.Logger) (_ *App, err error) {
+	shutdownTracing, err := tracing.Init(context.Background(), config.TracingConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init tracing: %w", err)
+	}
+	// The exporter started above is only handed to App on success, any later
+	// failure has to stop it here.
+	defer func() {
+		if err == nil {
+			return
+		}
+		if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+			logger.Warn("failed to shut down tracing", zap.Error(shutdownErr))
+		}
+	}()
+
+	migrator := repository.NewMigration(config.DatabaseConfig)
 
 	apodImagesRepository, err := repository.InitDB(config.DatabaseConfig, migrator, logger)
 	if err != nil {
-		logger.With(
-			zap.String("place", "main"),
-			zap.Error(err),
-		).Panic("Failed to establish database connection")
+		return nil, err
 	}
 
-	apodImagesService := service.NewApodImagesService(logger, apodImagesRepository)
-	apodWorker := service.NewAPODWorker(apodImagesService, config.NasaApiKey, config.WorkerConfig, logger)
-	apodImagesHandler := handler.NewApodImagesHandler(apodImagesService, logger)
+	imageStorage, err := initStorage(config.StorageConfig, logger)
+	if err != nil {
+		apodImagesRepository.Close()
+		return nil, fmt.Errorf("failed to init storage: %w", err)
+	}
+
+	apodImagesService := service.NewApodImagesService(logger, apodImagesRepository, imageStorage, config.WorkerConfig)
+	apodWorker, err := service.NewAPODWorker(apodImagesService, apodImagesRepository, config.NasaApiKey.Value(), config.WorkerConfig, logger)
+	if err != nil {
+		apodImagesRepository.Close()
+		return nil, fmt.Errorf("failed to init worker: %w", err)
+	}
+
+	return &App{
+		config:          config,
+		migrator:        migrator,
+		repo:            apodImagesRepository,
+		storage:         imageStorage,
+		service:         apodImagesService,
+		worker:          apodWorker,
+		shutdownTracing: shutdownTracing,
+		logger:          logger,
+	}, nil
+}
+
+func (app *App) newServer() (*server.Server, error) {
+	config, logger := app.config, app.logger
+	apodImagesHandler := handler.NewApodImagesHandler(app.service, config.ServerConfig.PublicURL, config.ServerConfig.TrustProxyHeaders, logger)
 
 	c := cors.New(cors.Options{
 		AllowCredentials: true,
 		AllowedMethods:   []string{http.MethodOptions, http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodPut, http.MethodPatch},
-		AllowedHeaders:   []string{"Content-Type", "Authorization", "Set-Cookie", "User-Agent", "Origin"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "Set-Cookie", "User-Agent", "Origin", "Range", "If-None-Match", "If-Modified-Since"},
+		ExposedHeaders:   []string{"ETag", "Content-Range", "Content-Length", "Link", "X-Next-Cursor"},
 		AllowOriginFunc: func(origin string) bool {
 			return true
 		},
@@ -43,17 +104,120 @@ func NewApp(config *config.Config, logger *zap.Logger) (*App, error) {
 
 	mux := mux.NewRouter()
 	apodImagesHandler.Init(mux)
-	handler := c.Handler(mux)
 
-	httpServer := server.NewServer(config.ServerConfig, handler)
+	if config.ServerConfig.AdminToken != "" {
+		handler.NewAdminHandler(app.worker, config.ServerConfig.AdminToken.Value(), logger).Init(mux)
+	} else {
+		logger.Warn("ADMIN_TOKEN is not set, admin API is disabled")
+	}
+	schemaVersion, err := app.migrator.LatestVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	healthService := service.NewHealthService(app.repo, app.storage, app.worker, schemaVersion, config.ServerConfig.MaxFetchAge, logger)
+	handler.NewHealthHandler(healthService).Init(mux)
 
-	go apodWorker.Start()
+	mux.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+	if err := metrics.RegisterStorageUsage(app.storage, logger); err != nil {
+		logger.Warn("Failed to register storage usage metrics", zap.Error(err))
+	}
+	handler := tracing.Instrument(mux, metrics.Instrument(mux, c.Handler(mux)))
 
-	return &App{
-		server: httpServer,
-	}, nil
+	return server.NewServer(config.ServerConfig, handler), nil
 }
 
+func initStorage(cfg config.StorageConfig, logger *zap.Logger) (storage.Storage, error) {
+	switch cfg.Backend {
+	case "local":
+		logger.Info("Using local image storage", zap.String("root", cfg.LocalRoot))
+		return local.NewLocalStorage(cfg.LocalRoot), nil
+	case "s3":
+		logger.Info("Using S3 image storage", zap.String("endpoint", cfg.S3Endpoint), zap.String("bucket", cfg.S3Bucket))
+		return s3.NewS3Storage(context.Background(), cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// Run serves HTTP and runs the worker until SIGINT/SIGTERM or a server
+// failure, then shuts everything down in order within one shutdown deadline.
 func (app *App) Run() error {
-	return app.server.Run()
+	httpServer, err := app.newServer()
+	if err != nil {
+		return errors.Join(err, app.Close())
+	}
+
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	workerCtx, cancelWorker := context.WithCancel(context.Background())
+	defer cancelWorker()
+
+	app.worker.Start(workerCtx)
+	serverErr := httpServer.Start()
+
+	var runErr error
+	select {
+	case <-signalCtx.Done():
+		app.logger.Info("Shutdown signal received")
+	case runErr = <-serverErr:
+		app.logger.Error("HTTP server stopped unexpectedly", zap.Error(runErr))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), app.config.ServerConfig.ShutdownTimeout)
+	defer cancel()
+
+	// Stop taking requests first, then abort the worker, and only then close
+	// the pool both of them were using.
+	var errs []error
+	if err := httpServer.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to shut down HTTP server: %w", err))
+	}
+
+	cancelWorker()
+	if err := app.worker.Wait(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := app.repo.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close database: %w", err))
+	}
+
+	if err := app.shutdownTracing(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to flush traces: %w", err))
+	}
+
+	if err := errors.Join(append([]error{runErr}, errs...)...); err != nil {
+		return err
+	}
+
+	app.logger.Info("Application stopped gracefully")
+	return nil
+}
+
+// Fetch saves the APOD for date, or the latest one when date is empty.
+func (app *App) Fetch(ctx context.Context, date string) error {
+	return app.worker.Fetch(ctx, date)
+}
+
+func (app *App) Backfill(ctx context.Context, startDate, endDate time.Time) error {
+	return app.worker.Backfill(ctx, startDate, endDate)
+}
+
+// Verify compares the stored files with the sizes and checksums in the database.
+func (app *App) Verify(ctx context.Context) (*domain.VerifyReport, error) {
+	return app.service.Verify(ctx)
+}
+
+// Export writes the stored entries between from and to as JSON lines.
+func (app *App) Export(ctx context.Context, w io.Writer, from, to string) (int, error) {
+	return app.service.Export(ctx, w, from, to)
+}
+
+// Close releases what NewApp opened, Run does this itself.
+func (app *App) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), app.config.ServerConfig.ShutdownTimeout)
+	defer cancel()
+
+	return errors.Join(app.repo.Close(), app.shutdownTracing(ctx))
 }
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/migration"
+	"nasa-apod-app/internal/repository"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+const (
+	MigrateUp      = "up"
+	MigrateDown    = "down"
+	MigrateStatus  = "status"
+	MigrateRedo    = "redo"
+	MigrateVersion = "version"
+)
+
+// Migrate runs one migration command against the configured database and
+// returns the schema version and migration status afterwards. Unlike NewApp it
+// connects without applying pending migrations first.
+func Migrate(ctx context.Context, cfg *config.Config, command string, logger *zap.Logger) (int64, []migration.Status, error) {
+	migrator := repository.NewMigration(cfg.DatabaseConfig)
+
+	var run func(context.Context, *sqlx.DB) error
+	switch command {
+	case MigrateUp:
+		run = migrator.Up
+	case MigrateDown:
+		run = migrator.Down
+	case MigrateRedo:
+		run = migrator.Redo
+	case MigrateStatus, MigrateVersion:
+	default:
+		return 0, nil, fmt.Errorf("unknown migrate command %q, use up, down, status, redo or version", command)
+	}
+
+	db, err := repository.Connect(cfg.DatabaseConfig, logger)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer db.Close()
+
+	if run != nil {
+		if err := run(ctx, db); err != nil {
+			return 0, nil, fmt.Errorf("migrate %s failed: %w", command, err)
+		}
+	}
+
+	version, err := migrator.Version(ctx, db)
+	if err != nil {
+		return 0, nil, err
+	}
+	statuses, err := migrator.Status(ctx, db)
+	if err != nil {
+		return 0, nil, err
+	}
+	return version, statuses, nil
+}
@@ -1,113 +1,275 @@
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"os"
-	"strconv"
+	"sort"
+	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+const redacted = "[REDACTED]"
+
 type Config struct {
 	DatabaseConfig DBConfig
 	ServerConfig   ServerConfig
 	WorkerConfig   WorkerConfig
-	NasaApiKey     string
+	StorageConfig  StorageConfig
+	TracingConfig  TracingConfig
+	NasaApiKey     Secret
 }
 
 type WorkerConfig struct {
-	RunTime            time.Time
-	RunFetchingOnStart bool
-	ApiURL             string
+	RunTime                time.Time
+	Schedules              []string
+	Timezone               *time.Location
+	RunFetchingOnStart     bool
+	ApiURL                 string
+	BackfillFrom           time.Time
+	BackfillTo             time.Time
+	BackfillBatchDays      int
+	DownloadHDImages       bool
+	ConflictPolicy         string
+	ImageVariants          []string
+	ImageProcessingWorkers int
+	MissedFetchRetry       time.Duration
+	Retry                  RetryConfig
+}
+
+type RetryConfig struct {
+	MaxAttempts          int
+	BaseDelay            time.Duration
+	MaxDelay             time.Duration
+	Jitter               float64
+	RetryableStatusCodes []int
 }
 
 type DBConfig struct {
+	Backend        string
+	SQLitePath     string
 	Host           string
 	Port           string
 	Username       string
-	Password       string
+	Password       Secret
 	DBName         string
 	ReconnRetry    int
 	TimeWaitPerTry time.Duration
+	AutoMigrate    bool
+}
+
+type StorageConfig struct {
+	Backend     string
+	LocalRoot   string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey Secret
+	S3SecretKey Secret
+	S3UseSSL    bool
+}
+
+type TracingConfig struct {
+	Exporter    string
+	ServiceName string
 }
 
 type ServerConfig struct {
-	Host string
-	Port string
-}
-
-func ParseConfigFromEnv() (*Config, error) {
-	dbConfig := DBConfig{
-		Host:           getEnvOrDefault("DB_HOST", "localhost"),
-		Port:           getEnvOrDefault("DB_PORT", "5432"),
-		Username:       getEnvOrDefault("DB_USERNAME", "user"),
-		Password:       getEnvOrDefault("DB_PASSWORD", "password"),
-		DBName:         getEnvOrDefault("DB_NAME", "database"),
-		ReconnRetry:    getEnvAsInt("DB_RECONN_RETRY", 3),
-		TimeWaitPerTry: getEnvAsDuration("DB_TIME_WAIT_PER_TRY", 5*time.Second),
+	Host              string
+	Port              string
+	PublicURL         string
+	TrustProxyHeaders bool
+	ShutdownTimeout   time.Duration
+	AdminToken        Secret
+	MaxFetchAge       time.Duration
+}
+
+// Loader builds the configuration from, in increasing precedence, defaults, a
+// YAML or TOML config file, environment variables and command line flags.
+type Loader struct {
+	flags      *flag.FlagSet
+	configFile *string
+	lookupEnv  func(string) (string, bool)
+}
+
+// NewLoader registers -config and one flag per setting on flags. Load must be
+// called after flags are parsed.
+func NewLoader(flags *flag.FlagSet) *Loader {
+	loader := &Loader{
+		flags:      flags,
+		configFile: flags.String("config", "", "path to a YAML or TOML config file, defaults to $CONFIG_FILE"),
+		lookupEnv:  os.LookupEnv,
 	}
 
-	serverConfig := ServerConfig{
-		Host: getEnvOrDefault("SERVER_HOST", "0.0.0.0"),
-		Port: getEnvOrDefault("SERVER_PORT", "8080"),
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		flags.Var(&flagValue{isBool: s.isBool}, s.flagName(), usage)
 	}
+	return loader
+}
 
-	NasaApiKey := os.Getenv("NASA_API_KEY")
+type rawValue struct {
+	value  string
+	source string
+}
 
-	workerConfig := WorkerConfig{
-		RunTime:            getEnvAsTime("WORKER_RUN_TIME", "03:00"),
-		RunFetchingOnStart: getEnvAsBool("RUN_FETCHING_ON_START", true),
-		ApiURL:             getEnvOrDefault("NASA_API_URL", "https://api.nasa.gov/planetary/apod?api_key="+os.Getenv("NASA_API_KEY")),
+// Load reads every source and validates the result. All invalid settings are
+// reported together instead of stopping at the first one.
+func (l *Loader) Load() (*Config, error) {
+	raw := make(map[string]rawValue, len(settings))
+	byKey := make(map[string]setting, len(settings))
+	byFlag := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		raw[s.env] = rawValue{value: s.def, source: "default"}
+		byKey[s.key] = s
+		byFlag[s.flagName()] = s
 	}
 
-	return &Config{
-		DatabaseConfig: dbConfig,
-		ServerConfig:   serverConfig,
-		WorkerConfig:   workerConfig,
-		NasaApiKey:     NasaApiKey,
-	}, nil
-}
+	var errs []error
 
-func getEnvOrDefault(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+	path := *l.configFile
+	if path == "" {
+		path, _ = l.lookupEnv("CONFIG_FILE")
 	}
-	return defaultValue
-}
+	if path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			s, ok := byKey[key]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s in %s: unknown setting", key, path))
+				continue
+			}
 
-func getEnvAsInt(name string, defaultValue int) int {
-	if valueStr, exists := os.LookupEnv(name); exists {
-		if value, err := strconv.Atoi(valueStr); err == nil {
-			return value
+			value := values[key]
+			if len(value) > 1 && s.sep == "" {
+				errs = append(errs, fmt.Errorf("%s in %s: expected a single value, not a list", key, path))
+				continue
+			}
+			raw[s.env] = rawValue{value: strings.Join(value, s.sep), source: key + " in " + path}
 		}
 	}
-	return defaultValue
-}
 
-func getEnvAsBool(name string, defaultValue bool) bool {
-	if valueStr, exists := os.LookupEnv(name); exists {
-		if value, err := strconv.ParseBool(valueStr); err == nil {
-			return value
+	for _, s := range settings {
+		value, ok := l.lookupEnv(s.env)
+		if ok {
+			raw[s.env] = rawValue{value: value, source: "env " + s.env}
+		}
+
+		if !s.secret {
+			continue
+		}
+
+		// Secrets can be mounted as files, e.g. DB_PASSWORD_FILE=/run/secrets/db_password.
+		path, fileSet := l.lookupEnv(s.env + "_FILE")
+		switch {
+		case !fileSet:
+		case ok:
+			errs = append(errs, fmt.Errorf("%s: set either %s or %s_FILE, not both", s.env, s.env, s.env))
+		default:
+			content, err := os.ReadFile(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_FILE: failed to read secret: %w", s.env, err))
+				continue
+			}
+			raw[s.env] = rawValue{value: strings.TrimRight(string(content), "\r\n"), source: "env " + s.env + "_FILE"}
+		}
+	}
+
+	l.flags.Visit(func(f *flag.Flag) {
+		if s, ok := byFlag[f.Name]; ok {
+			raw[s.env] = rawValue{value: f.Value.String(), source: "flag -" + f.Name}
+		}
+	})
+
+	cfg := &Config{}
+	for _, s := range settings {
+		value := raw[s.env]
+		if err := s.parse(cfg, strings.TrimSpace(value.value)); err != nil {
+			errs = append(errs, fmt.Errorf("%s (from %s): %w", s.env, value.source, err))
 		}
 	}
-	return defaultValue
+	if len(errs) == 0 {
+		errs = validate(cfg)
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return cfg, nil
 }
 
-func getEnvAsTime(name string, defaultValue string) time.Time {
-	if valueStr, exists := os.LookupEnv(name); exists {
-		if value, err := time.Parse("15:04", valueStr); err == nil {
-			return value
+// Write prints cfg in the config file layout, with every secret that is set
+// replaced by a placeholder.
+func Write(w io.Writer, cfg *Config) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range settings {
+		value := s.format(cfg)
+		if s.secret && value != "" {
+			value = redacted
 		}
+
+		parent := root
+		path := strings.Split(s.key, ".")
+		for _, section := range path[:len(path)-1] {
+			parent = mappingChild(parent, section)
+		}
+
+		scalar := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+		if value == "" {
+			scalar.Style = yaml.DoubleQuotedStyle
+		}
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: path[len(path)-1]}, scalar)
 	}
-	if defaultTime, err := time.Parse("15:04", defaultValue); err == nil {
-		return defaultTime
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return err
 	}
-	return time.Time{}
+	return encoder.Close()
 }
 
-func getEnvAsDuration(name string, defaultValue time.Duration) time.Duration {
-	if valueStr, exists := os.LookupEnv(name); exists {
-		if value, err := time.ParseDuration(valueStr); err == nil {
-			return value
+func mappingChild(parent *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			return parent.Content[i+1]
 		}
 	}
-	return defaultValue
+
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+	return child
+}
+
+// flagValue keeps the raw flag text, it is parsed with the other sources.
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (f *flagValue) String() string {
+	return f.value
+}
+
+func (f *flagValue) Set(value string) error {
+	f.value = value
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
 }
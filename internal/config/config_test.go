@@ -0,0 +1,175 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLoader(t *testing.T, env map[string]string, args ...string) *Loader {
+	t.Helper()
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	loader := NewLoader(flags)
+	loader.lookupEnv = func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	assert.NoError(t, flags.Parse(args))
+	return loader
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := newTestLoader(t, nil).Load()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "5432", cfg.DatabaseConfig.Port)
+	assert.Equal(t, "03:00", cfg.WorkerConfig.RunTime.Format(timeOfDayLayout))
+	assert.Equal(t, []string{"320:jpeg", "1024:jpeg"}, cfg.WorkerConfig.ImageVariants)
+	assert.Equal(t, []int{408, 429, 500, 502, 503, 504}, cfg.WorkerConfig.Retry.RetryableStatusCodes)
+	assert.Equal(t, time.Local, cfg.WorkerConfig.Timezone)
+	assert.True(t, cfg.WorkerConfig.RunFetchingOnStart)
+	assert.Nil(t, cfg.WorkerConfig.Schedules)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+database:
+  host: file-host
+  port: 6000
+  name: file-db
+server:
+  port: 9000
+worker:
+  schedule:
+    - "0 3 * * *"
+    - "0 15 * * *"
+  download_hd_images: true
+`)
+
+	env := map[string]string{"CONFIG_FILE": path, "DB_PORT": "7000", "DB_NAME": "env-db"}
+	cfg, err := newTestLoader(t, env, "-db-name", "flag-db", "-run-fetching-on-start=false").Load()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "file-host", cfg.DatabaseConfig.Host, "file overrides default")
+	assert.Equal(t, "9000", cfg.ServerConfig.Port)
+	assert.Equal(t, "7000", cfg.DatabaseConfig.Port, "env overrides file")
+	assert.Equal(t, "flag-db", cfg.DatabaseConfig.DBName, "flag overrides env")
+	assert.False(t, cfg.WorkerConfig.RunFetchingOnStart)
+	assert.True(t, cfg.WorkerConfig.DownloadHDImages)
+	assert.Equal(t, []string{"0 3 * * *", "0 15 * * *"}, cfg.WorkerConfig.Schedules)
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+[worker]
+run_time = 04:30:00
+backfill_start_date = 2024-01-01
+image_variants = ["640:webp", "1280:jpeg"]
+
+[retry]
+jitter = 0.5
+status_codes = [429, 503]
+
+[storage.s3]
+bucket = "images"
+`)
+
+	cfg, err := newTestLoader(t, nil, "-config", path).Load()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "04:30", cfg.WorkerConfig.RunTime.Format(timeOfDayLayout))
+	assert.Equal(t, "2024-01-01", cfg.WorkerConfig.BackfillFrom.Format(dateLayout))
+	assert.Equal(t, []string{"640:webp", "1280:jpeg"}, cfg.WorkerConfig.ImageVariants)
+	assert.Equal(t, 0.5, cfg.WorkerConfig.Retry.Jitter)
+	assert.Equal(t, []int{429, 503}, cfg.WorkerConfig.Retry.RetryableStatusCodes)
+	assert.Equal(t, "images", cfg.StorageConfig.S3Bucket)
+}
+
+func TestLoadReportsEveryInvalidSetting(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+database:
+  hots: typo
+worker:
+  conflict_policy: overwrite
+`)
+
+	env := map[string]string{
+		"WORKER_RUN_TIME":  "25:99",
+		"RETRY_JITTER":     "2",
+		"IMAGE_VARIANTS":   "320:gif",
+		"STORAGE_BACKEND":  "s3",
+		"SHUTDOWN_TIMEOUT": "soon",
+		"WORKER_SCHEDULE":  "0 3 * * *; 0 25 * * *",
+	}
+	_, err := newTestLoader(t, env, "-config", path, "-db-port", "99999").Load()
+	assert.Error(t, err)
+
+	for _, expected := range []string{
+		"database.hots in " + path + ": unknown setting",
+		"SAVE_CONFLICT_POLICY (from worker.conflict_policy in " + path + `): "overwrite" is not one of skip, refresh`,
+		`WORKER_RUN_TIME (from env WORKER_RUN_TIME): "25:99" is not a time of day, use HH:MM`,
+		`RETRY_JITTER (from env RETRY_JITTER): 2 is outside of 0-1`,
+		`IMAGE_VARIANTS (from env IMAGE_VARIANTS): "gif" is not one of jpeg, png, webp`,
+		`SHUTDOWN_TIMEOUT (from env SHUTDOWN_TIMEOUT): "soon" is not a duration, use e.g. 30s or 1h`,
+		`WORKER_SCHEDULE (from env WORKER_SCHEDULE): "0 25 * * *" is not a cron expression`,
+		`DB_PORT (from flag -db-port): "99999" is not a port number (1-65535)`,
+	} {
+		assert.Contains(t, err.Error(), expected)
+	}
+}
+
+func TestLoadCrossFieldValidation(t *testing.T) {
+	env := map[string]string{
+		"BACKFILL_START_DATE": "2024-02-01",
+		"BACKFILL_END_DATE":   "2024-01-01",
+		"STORAGE_BACKEND":     "s3",
+	}
+	_, err := newTestLoader(t, env).Load()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "BACKFILL_END_DATE 2024-01-01 is before BACKFILL_START_DATE 2024-02-01")
+	assert.Contains(t, err.Error(), "S3_ENDPOINT is required with the s3 storage backend")
+}
+
+func TestWrite(t *testing.T) {
+	env := map[string]string{
+		"DB_PASSWORD":     "hunter2",
+		"NASA_API_KEY":    "nasa-key",
+		"WORKER_SCHEDULE": "0 3 * * *;0 15 * * *",
+		"WORKER_TIMEZONE": "Europe/Berlin",
+	}
+	cfg, err := newTestLoader(t, env).Load()
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	assert.NoError(t, Write(&out, cfg))
+	assert.NotContains(t, out.String(), "hunter2")
+	assert.NotContains(t, out.String(), "nasa-key")
+	assert.Contains(t, out.String(), "password: '[REDACTED]'")
+	assert.Contains(t, out.String(), `admin_token: ""`)
+
+	// The output is a valid config file that loads back to the same values.
+	printed := strings.ReplaceAll(out.String(), "'[REDACTED]'", `""`)
+	reloaded, err := newTestLoader(t, nil, "-config", writeConfigFile(t, "printed.yaml", printed)).Load()
+	assert.NoError(t, err)
+
+	cfg.DatabaseConfig.Password, cfg.NasaApiKey = "", ""
+	assert.Equal(t, cfg, reloaded)
+}
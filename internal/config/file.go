@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// readConfigFile flattens a YAML or TOML file into dotted setting keys such as
+// "worker.run_time". Lists keep their items so they can be joined with the
+// separator of the setting.
+func readConfigFile(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := make(map[string][]string)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var document yaml.Node
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if len(document.Content) == 0 {
+			return values, nil
+		}
+		if err := flattenYAML(document.Content[0], "", values); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	case ".toml":
+		var document map[string]interface{}
+		if err := toml.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if err := flattenTOML(document, "", values); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file %s, use .yaml, .yml or .toml", path)
+	}
+	return values, nil
+}
+
+// flattenYAML keeps scalars as written, so "03:00" or "0.20" reach the setting
+// parsers untouched.
+func flattenYAML(node *yaml.Node, prefix string, values map[string][]string) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := flattenYAML(node.Content[i+1], joinKey(prefix, node.Content[i].Value), values); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("%s: lists may only hold plain values", prefix)
+			}
+			items = append(items, item.Value)
+		}
+		values[prefix] = items
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			values[prefix] = []string{""}
+			return nil
+		}
+		values[prefix] = []string{node.Value}
+	default:
+		return fmt.Errorf("%s: unsupported value", prefix)
+	}
+	return nil
+}
+
+func flattenTOML(table map[string]interface{}, prefix string, values map[string][]string) error {
+	for key, value := range table {
+		key = joinKey(prefix, key)
+
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if err := flattenTOML(value, key, values); err != nil {
+				return err
+			}
+		case []interface{}:
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				items = append(items, tomlScalar(item))
+			}
+			values[key] = items
+		default:
+			values[key] = []string{tomlScalar(value)}
+		}
+	}
+	return nil
+}
+
+// tomlScalar turns TOML's local dates and times back into the layouts the
+// settings use, so run_time = 03:00:00 works as well as "03:00".
+func tomlScalar(value interface{}) string {
+	t, ok := value.(time.Time)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+
+	// The decoder marks local values with these zone names.
+	switch t.Location().String() {
+	case "time-local":
+		return t.Format(timeOfDayLayout)
+	case "date-local":
+		return t.Format(dateLayout)
+	default:
+		return t.Format(time.RFC3339)
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
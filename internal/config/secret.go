@@ -0,0 +1,148 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// minRedactedLength keeps very short secrets out of the log redaction, replacing
+// every occurrence of them would garble unrelated log lines.
+const minRedactedLength = 4
+
+// sensitiveQueryParams are redacted from URLs before they are logged or stored.
+var sensitiveQueryParams = []string{"api_key", "apikey", "key", "token", "access_token", "password", "secret", "signature", "x-amz-signature", "x-amz-credential"}
+
+// Secret is a configuration value that must not end up in logs. Printing it
+// with fmt, zap or encoding/json shows [REDACTED], Value returns the real value.
+type Secret string
+
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return `config.Secret("` + s.String() + `")`
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// RedactURL hides the password of the user info and the values of sensitive
+// query parameters such as api_key. Values that are not URLs are returned as is.
+func RedactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.User == nil && parsed.RawQuery == "") {
+		return rawURL
+	}
+
+	if _, hasPassword := parsed.User.Password(); hasPassword {
+		parsed.User = url.UserPassword(parsed.User.Username(), "REDACTED")
+	}
+
+	query := parsed.Query()
+	for name := range query {
+		for _, sensitive := range sensitiveQueryParams {
+			if strings.EqualFold(name, sensitive) {
+				query.Set(name, "REDACTED")
+			}
+		}
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+// RedactURLError hides sensitive parts of the URL a *url.Error carries, the
+// HTTP client puts the complete request URL into its error messages.
+func RedactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = RedactURL(urlErr.URL)
+	}
+	return err
+}
+
+// Secrets returns the values of every secret setting that is set.
+func (c *Config) Secrets() []string {
+	var secrets []string
+	for _, s := range settings {
+		if value := s.format(c); s.secret && value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
+
+// RedactLogger replaces every occurrence of the configured secrets in messages
+// and string, error and stringer fields with [REDACTED]. It is a safety net for
+// log sites that format URLs or connection strings themselves.
+func RedactLogger(logger *zap.Logger, cfg *Config) *zap.Logger {
+	var pairs []string
+	for _, secret := range cfg.Secrets() {
+		if len(secret) >= minRedactedLength {
+			pairs = append(pairs, secret, redacted)
+		}
+	}
+	if len(pairs) == 0 {
+		return logger
+	}
+
+	replacer := strings.NewReplacer(pairs...)
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &redactingCore{Core: core, replacer: replacer}
+	}))
+}
+
+type redactingCore struct {
+	zapcore.Core
+	replacer *strings.Replacer
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redact(fields)), replacer: c.replacer}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.replacer.Replace(entry.Message)
+	return c.Core.Write(entry, c.redact(fields))
+}
+
+func (c *redactingCore) redact(fields []zapcore.Field) []zapcore.Field {
+	redactedFields := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		switch field.Type {
+		case zapcore.StringType:
+			field.String = c.replacer.Replace(field.String)
+		case zapcore.ErrorType:
+			if err, ok := field.Interface.(error); ok {
+				field = zap.String(field.Key, c.replacer.Replace(err.Error()))
+			}
+		case zapcore.StringerType:
+			if stringer, ok := field.Interface.(fmt.Stringer); ok {
+				field = zap.String(field.Key, c.replacer.Replace(stringer.String()))
+			}
+		}
+		redactedFields[i] = field
+	}
+	return redactedFields
+}
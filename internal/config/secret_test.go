@@ -0,0 +1,103 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSecretFormatting(t *testing.T) {
+	secret := Secret("hunter22")
+
+	assert.Equal(t, "hunter22", secret.Value())
+	assert.Equal(t, redacted, fmt.Sprint(secret))
+	assert.Equal(t, redacted, fmt.Sprintf("%v", secret))
+	assert.NotContains(t, fmt.Sprintf("%#v", secret), "hunter22")
+	assert.NotContains(t, fmt.Sprintf("%+v", DBConfig{Password: secret}), "hunter22")
+
+	encoded, err := json.Marshal(map[string]Secret{"password": secret})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"password":"[REDACTED]"}`, string(encoded))
+
+	assert.Equal(t, "", Secret("").String())
+}
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://api.nasa.gov/planetary/apod?api_key=abc123&date=2024-01-02", "https://api.nasa.gov/planetary/apod?api_key=REDACTED&date=2024-01-02"},
+		{"https://example.com/a?Token=t&X-Amz-Signature=s", "https://example.com/a?Token=REDACTED&X-Amz-Signature=REDACTED"},
+		{"postgres://apod:hunter22@db:5432/apod?sslmode=disable", "postgres://apod:REDACTED@db:5432/apod?sslmode=disable"},
+		{"https://example.com/image.jpg", "https://example.com/image.jpg"},
+		{"not a url", "not a url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			assert.Equal(t, tt.want, RedactURL(tt.in))
+		})
+	}
+}
+
+func TestRedactURLError(t *testing.T) {
+	var err error = &url.Error{
+		Op:  "Get",
+		URL: "https://api.nasa.gov/planetary/apod?api_key=abc123",
+		Err: errors.New("connection refused"),
+	}
+
+	err = RedactURLError(err)
+	assert.NotContains(t, err.Error(), "abc123")
+	assert.Contains(t, err.Error(), "api_key=REDACTED")
+}
+
+func TestRedactLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	cfg := &Config{
+		NasaApiKey:     "nasa-key-123",
+		DatabaseConfig: DBConfig{Password: "hunter22"},
+		ServerConfig:   ServerConfig{AdminToken: "abc"},
+	}
+
+	logger := RedactLogger(zap.New(core), cfg).With(zap.String("dsn", "postgres://apod:hunter22@db/apod"))
+	logger.Info("calling ?api_key=nasa-key-123",
+		zap.Error(errors.New("auth failed for hunter22")),
+		zap.Stringer("url", &url.URL{Scheme: "https", Host: "api.nasa.gov", RawQuery: "api_key=nasa-key-123"}),
+		zap.String("token", "abc"),
+	)
+
+	entries := logs.AllUntimed()
+	if assert.Len(t, entries, 1) {
+		entry := entries[0]
+		assert.Equal(t, "calling ?api_key="+redacted, entry.Message)
+
+		fields := entry.ContextMap()
+		assert.Equal(t, "postgres://apod:"+redacted+"@db/apod", fields["dsn"])
+		assert.Equal(t, "auth failed for "+redacted, fields["error"])
+		assert.NotContains(t, fields["url"], "nasa-key-123")
+		assert.Equal(t, "abc", fields["token"], "secrets shorter than four characters are left alone")
+	}
+}
+
+func TestLoadSecretFromFile(t *testing.T) {
+	path := writeConfigFile(t, "db_password", "from-file\n")
+
+	cfg, err := newTestLoader(t, map[string]string{"DB_PASSWORD_FILE": path}).Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "from-file", cfg.DatabaseConfig.Password.Value())
+
+	_, err = newTestLoader(t, map[string]string{"DB_PASSWORD": "x", "DB_PASSWORD_FILE": path}).Load()
+	assert.ErrorContains(t, err, "set either DB_PASSWORD or DB_PASSWORD_FILE")
+
+	_, err = newTestLoader(t, map[string]string{"NASA_API_KEY_FILE": path + ".missing"}).Load()
+	assert.ErrorContains(t, err, "NASA_API_KEY_FILE: failed to read secret")
+}
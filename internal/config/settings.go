@@ -0,0 +1,418 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	timeOfDayLayout = "15:04"
+	dateLayout      = "2006-01-02"
+)
+
+// setting is a single configuration value together with everywhere it can be
+// set from. The command line flag is derived from the environment variable,
+// e.g. WORKER_RUN_TIME becomes -worker-run-time.
+type setting struct {
+	key   string // path in the config file, e.g. "worker.run_time"
+	env   string
+	def   string
+	usage string
+	codec
+}
+
+type codec struct {
+	parse  func(cfg *Config, value string) error
+	format func(cfg *Config) string
+	// sep joins list values given as a list in the config file.
+	sep    string
+	isBool bool
+	secret bool
+}
+
+func (s setting) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(s.env), "_", "-")
+}
+
+var settings = []setting{
+	{key: "database.backend", env: "DB_BACKEND", def: "postgres", usage: "database: postgres or sqlite",
+		codec: stringValue(func(c *Config) *string { return &c.DatabaseConfig.Backend }, oneOf("postgres", "sqlite"))},
+	{key: "database.sqlite_path", env: "DB_SQLITE_PATH", def: "./apod.db", usage: "database file of the sqlite backend",
+		codec: stringValue(func(c *Config) *string { return &c.DatabaseConfig.SQLitePath }, notEmpty)},
+	{key: "database.host", env: "DB_HOST", def: "localhost", usage: "PostgreSQL host",
+		codec: stringValue(func(c *Config) *string { return &c.DatabaseConfig.Host }, notEmpty)},
+	{key: "database.port", env: "DB_PORT", def: "5432", usage: "PostgreSQL port",
+		codec: stringValue(func(c *Config) *string { return &c.DatabaseConfig.Port }, port)},
+	{key: "database.username", env: "DB_USERNAME", def: "user", usage: "PostgreSQL user",
+		codec: stringValue(func(c *Config) *string { return &c.DatabaseConfig.Username }, notEmpty)},
+	{key: "database.password", env: "DB_PASSWORD", def: "password", usage: "PostgreSQL password",
+		codec: secretValue(func(c *Config) *Secret { return &c.DatabaseConfig.Password })},
+	{key: "database.name", env: "DB_NAME", def: "database", usage: "PostgreSQL database name",
+		codec: stringValue(func(c *Config) *string { return &c.DatabaseConfig.DBName }, notEmpty)},
+	{key: "database.reconnect_retries", env: "DB_RECONN_RETRY", def: "3", usage: "connection attempts on startup",
+		codec: intValue(func(c *Config) *int { return &c.DatabaseConfig.ReconnRetry }, 1)},
+	{key: "database.reconnect_wait", env: "DB_TIME_WAIT_PER_TRY", def: "5s", usage: "wait between connection attempts",
+		codec: durationValue(func(c *Config) *time.Duration { return &c.DatabaseConfig.TimeWaitPerTry }, 0)},
+	{key: "database.auto_migrate", env: "DB_AUTO_MIGRATE", def: "true", usage: "apply pending migrations on startup",
+		codec: boolValue(func(c *Config) *bool { return &c.DatabaseConfig.AutoMigrate })},
+
+	{key: "server.host", env: "SERVER_HOST", def: "0.0.0.0", usage: "address the HTTP server listens on",
+		codec: stringValue(func(c *Config) *string { return &c.ServerConfig.Host })},
+	{key: "server.port", env: "SERVER_PORT", def: "8080", usage: "port the HTTP server listens on",
+		codec: stringValue(func(c *Config) *string { return &c.ServerConfig.Port }, port)},
+	{key: "server.public_url", env: "SERVER_PUBLIC_URL", def: "", usage: "base URL of image links, derived from the request when empty",
+		codec: stringValue(func(c *Config) *string { return &c.ServerConfig.PublicURL }, absoluteURL)},
+	{key: "server.trust_proxy_headers", env: "SERVER_TRUST_PROXY_HEADERS", def: "false", usage: "derive image links from X-Forwarded-Proto and X-Forwarded-Host when server.public_url is empty",
+		codec: boolValue(func(c *Config) *bool { return &c.ServerConfig.TrustProxyHeaders })},
+	{key: "server.shutdown_timeout", env: "SHUTDOWN_TIMEOUT", def: "30s", usage: "time allowed for a graceful shutdown",
+		codec: durationValue(func(c *Config) *time.Duration { return &c.ServerConfig.ShutdownTimeout }, time.Nanosecond)},
+	{key: "server.admin_token", env: "ADMIN_TOKEN", def: "", usage: "bearer token of the admin API, disabled when empty",
+		codec: secretValue(func(c *Config) *Secret { return &c.ServerConfig.AdminToken })},
+	{key: "server.readiness_max_fetch_age", env: "READINESS_MAX_FETCH_AGE", def: "48h", usage: "maximum age of the last successful fetch for /readyz, 0 disables the check",
+		codec: durationValue(func(c *Config) *time.Duration { return &c.ServerConfig.MaxFetchAge }, 0)},
+
+	{key: "nasa.api_key", env: "NASA_API_KEY", def: "", usage: "NASA API key",
+		codec: secretValue(func(c *Config) *Secret { return &c.NasaApiKey })},
+	{key: "nasa.api_url", env: "NASA_API_URL", def: "https://api.nasa.gov/planetary/apod", usage: "NASA APOD API endpoint",
+		codec: stringValue(func(c *Config) *string { return &c.WorkerConfig.ApiURL }, notEmpty, absoluteURL)},
+
+	{key: "worker.run_time", env: "WORKER_RUN_TIME", def: "03:00", usage: "daily fetch time (HH:MM) when no schedule is set",
+		codec: timeOfDayValue(func(c *Config) *time.Time { return &c.WorkerConfig.RunTime })},
+	{key: "worker.schedule", env: "WORKER_SCHEDULE", def: "", usage: "semicolon separated cron expressions replacing the daily run time",
+		codec: listValue(func(c *Config) *[]string { return &c.WorkerConfig.Schedules }, ";", cronExpression)},
+	{key: "worker.timezone", env: "WORKER_TIMEZONE", def: "Local", usage: "IANA time zone of the schedule",
+		codec: locationValue(func(c *Config) **time.Location { return &c.WorkerConfig.Timezone })},
+	{key: "worker.run_on_start", env: "RUN_FETCHING_ON_START", def: "true", usage: "fetch once right after startup",
+		codec: boolValue(func(c *Config) *bool { return &c.WorkerConfig.RunFetchingOnStart })},
+	{key: "worker.backfill_start_date", env: "BACKFILL_START_DATE", def: "", usage: "backfill missing days from this date (YYYY-MM-DD) on startup",
+		codec: dateValue(func(c *Config) *time.Time { return &c.WorkerConfig.BackfillFrom })},
+	{key: "worker.backfill_end_date", env: "BACKFILL_END_DATE", def: "", usage: "last day (YYYY-MM-DD) of the startup backfill, defaults to today",
+		codec: dateValue(func(c *Config) *time.Time { return &c.WorkerConfig.BackfillTo })},
+	{key: "worker.backfill_batch_days", env: "BACKFILL_BATCH_DAYS", def: "30", usage: "days requested from NASA at once during backfills",
+		codec: intValue(func(c *Config) *int { return &c.WorkerConfig.BackfillBatchDays }, 1)},
+	{key: "worker.download_hd_images", env: "DOWNLOAD_HD_IMAGES", def: "false", usage: "also download the HD image",
+		codec: boolValue(func(c *Config) *bool { return &c.WorkerConfig.DownloadHDImages })},
+	{key: "worker.conflict_policy", env: "SAVE_CONFLICT_POLICY", def: "skip", usage: "what saving a stored date does: skip or refresh",
+		codec: stringValue(func(c *Config) *string { return &c.WorkerConfig.ConflictPolicy }, oneOf("skip", "refresh"))},
+	{key: "worker.image_variants", env: "IMAGE_VARIANTS", def: "320:jpeg,1024:jpeg", usage: "comma separated resized variants as WIDTH:FORMAT",
+		codec: listValue(func(c *Config) *[]string { return &c.WorkerConfig.ImageVariants }, ",", imageVariant)},
+	{key: "worker.image_processing_workers", env: "IMAGE_PROCESSING_WORKERS", def: "2", usage: "variants resized at once",
+		codec: intValue(func(c *Config) *int { return &c.WorkerConfig.ImageProcessingWorkers }, 1)},
+	{key: "worker.missed_fetch_retry", env: "MISSED_FETCH_RETRY_INTERVAL", def: "1h", usage: "wait before retrying a failed daily fetch",
+		codec: durationValue(func(c *Config) *time.Duration { return &c.WorkerConfig.MissedFetchRetry }, 0)},
+
+	{key: "retry.max_attempts", env: "RETRY_MAX_ATTEMPTS", def: "5", usage: "HTTP attempts per request",
+		codec: intValue(func(c *Config) *int { return &c.WorkerConfig.Retry.MaxAttempts }, 1)},
+	{key: "retry.base_delay", env: "RETRY_BASE_DELAY", def: "1s", usage: "delay before the first retry, doubled after every attempt",
+		codec: durationValue(func(c *Config) *time.Duration { return &c.WorkerConfig.Retry.BaseDelay }, 0)},
+	{key: "retry.max_delay", env: "RETRY_MAX_DELAY", def: "1m", usage: "upper bound of the retry delay, a longer Retry-After ends the retries",
+		codec: durationValue(func(c *Config) *time.Duration { return &c.WorkerConfig.Retry.MaxDelay }, 0)},
+	{key: "retry.jitter", env: "RETRY_JITTER", def: "0.2", usage: "random fraction (0-1) added to or removed from every delay",
+		codec: floatValue(func(c *Config) *float64 { return &c.WorkerConfig.Retry.Jitter }, 0, 1)},
+	{key: "retry.status_codes", env: "RETRY_STATUS_CODES", def: "408,429,500,502,503,504", usage: "comma separated HTTP status codes that are retried",
+		codec: intListValue(func(c *Config) *[]int { return &c.WorkerConfig.Retry.RetryableStatusCodes }, 100, 599)},
+
+	{key: "storage.backend", env: "STORAGE_BACKEND", def: "local", usage: "image storage: local or s3",
+		codec: stringValue(func(c *Config) *string { return &c.StorageConfig.Backend }, oneOf("local", "s3"))},
+	{key: "storage.local_root", env: "STORAGE_LOCAL_ROOT", def: "./storage", usage: "directory of the local storage",
+		codec: stringValue(func(c *Config) *string { return &c.StorageConfig.LocalRoot })},
+	{key: "storage.s3.endpoint", env: "S3_ENDPOINT", def: "", usage: "S3-compatible endpoint, e.g. s3.amazonaws.com",
+		codec: stringValue(func(c *Config) *string { return &c.StorageConfig.S3Endpoint })},
+	{key: "storage.s3.region", env: "S3_REGION", def: "us-east-1", usage: "S3 region",
+		codec: stringValue(func(c *Config) *string { return &c.StorageConfig.S3Region })},
+	{key: "storage.s3.bucket", env: "S3_BUCKET", def: "apod", usage: "S3 bucket",
+		codec: stringValue(func(c *Config) *string { return &c.StorageConfig.S3Bucket })},
+	{key: "storage.s3.access_key", env: "S3_ACCESS_KEY", def: "", usage: "S3 access key",
+		codec: secretValue(func(c *Config) *Secret { return &c.StorageConfig.S3AccessKey })},
+	{key: "storage.s3.secret_key", env: "S3_SECRET_KEY", def: "", usage: "S3 secret key",
+		codec: secretValue(func(c *Config) *Secret { return &c.StorageConfig.S3SecretKey })},
+	{key: "storage.s3.use_ssl", env: "S3_USE_SSL", def: "true", usage: "connect to S3 over HTTPS",
+		codec: boolValue(func(c *Config) *bool { return &c.StorageConfig.S3UseSSL })},
+
+	{key: "tracing.exporter", env: "OTEL_TRACES_EXPORTER", def: "none", usage: "trace exporter: otlp or none",
+		codec: stringValue(func(c *Config) *string { return &c.TracingConfig.Exporter }, oneOf("none", "otlp"))},
+	{key: "tracing.service_name", env: "OTEL_SERVICE_NAME", def: "nasa-apod-app", usage: "service name reported with traces",
+		codec: stringValue(func(c *Config) *string { return &c.TracingConfig.ServiceName }, notEmpty)},
+}
+
+// validate checks rules spanning several settings.
+func validate(cfg *Config) []error {
+	var errs []error
+
+	worker := cfg.WorkerConfig
+	if !worker.BackfillFrom.IsZero() && !worker.BackfillTo.IsZero() && worker.BackfillTo.Before(worker.BackfillFrom) {
+		errs = append(errs, fmt.Errorf("BACKFILL_END_DATE %s is before BACKFILL_START_DATE %s", worker.BackfillTo.Format(dateLayout), worker.BackfillFrom.Format(dateLayout)))
+	}
+	if worker.Retry.BaseDelay > worker.Retry.MaxDelay {
+		errs = append(errs, fmt.Errorf("RETRY_BASE_DELAY %s is longer than RETRY_MAX_DELAY %s", worker.Retry.BaseDelay, worker.Retry.MaxDelay))
+	}
+
+	if cfg.StorageConfig.Backend == "s3" {
+		if cfg.StorageConfig.S3Endpoint == "" {
+			errs = append(errs, fmt.Errorf("S3_ENDPOINT is required with the s3 storage backend"))
+		}
+		if cfg.StorageConfig.S3Bucket == "" {
+			errs = append(errs, fmt.Errorf("S3_BUCKET is required with the s3 storage backend"))
+		}
+	}
+	return errs
+}
+
+func stringValue(field func(*Config) *string, checks ...func(string) error) codec {
+	return codec{
+		parse: func(cfg *Config, value string) error {
+			for _, check := range checks {
+				if err := check(value); err != nil {
+					return err
+				}
+			}
+			*field(cfg) = value
+			return nil
+		},
+		format: func(cfg *Config) string { return *field(cfg) },
+	}
+}
+
+func secretValue(field func(*Config) *Secret) codec {
+	return codec{
+		parse: func(cfg *Config, value string) error {
+			*field(cfg) = Secret(value)
+			return nil
+		},
+		format: func(cfg *Config) string { return field(cfg).Value() },
+		secret: true,
+	}
+}
+
+func intValue(field func(*Config) *int, minValue int) codec {
+	return codec{
+		parse: func(cfg *Config, value string) error {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%q is not a whole number", value)
+			}
+			if parsed < minValue {
+				return fmt.Errorf("%d is below the minimum of %d", parsed, minValue)
+			}
+			*field(cfg) = parsed
+			return nil
+		},
+		format: func(cfg *Config) string { return strconv.Itoa(*field(cfg)) },
+	}
+}
+
+func floatValue(field func(*Config) *float64, minValue, maxValue float64) codec {
+	return codec{
+		parse: func(cfg *Config, value string) error {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("%q is not a number", value)
+			}
+			if parsed < minValue || parsed > maxValue {
+				return fmt.Errorf("%g is outside of %g-%g", parsed, minValue, maxValue)
+			}
+			*field(cfg) = parsed
+			return nil
+		},
+		format: func(cfg *Config) string { return strconv.FormatFloat(*field(cfg), 'g', -1, 64) },
+	}
+}
+
+func boolValue(field func(*Config) *bool) codec {
+	return codec{
+		parse: func(cfg *Config, value string) error {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%q is not a boolean, use true or false", value)
+			}
+			*field(cfg) = parsed
+			return nil
+		},
+		format: func(cfg *Config) string { return strconv.FormatBool(*field(cfg)) },
+		isBool: true,
+	}
+}
+
+func durationValue(field func(*Config) *time.Duration, minValue time.Duration) codec {
+	return codec{
+		parse: func(cfg *Config, value string) error {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("%q is not a duration, use e.g. 30s or 1h", value)
+			}
+			if parsed < minValue {
+				return fmt.Errorf("%s is below the minimum of %s", parsed, minValue)
+			}
+			*field(cfg) = parsed
+			return nil
+		},
+		format: func(cfg *Config) string { return field(cfg).String() },
+	}
+}
+
+func timeOfDayValue(field func(*Config) *time.Time) codec {
+	return codec{
+		parse: func(cfg *Config, value string) error {
+			parsed, err := time.Parse(timeOfDayLayout, value)
+			if err != nil {
+				return fmt.Errorf("%q is not a time of day, use HH:MM", value)
+			}
+			*field(cfg) = parsed
+			return nil
+		},
+		format: func(cfg *Config) string { return field(cfg).Format(timeOfDayLayout) },
+	}
+}
+
+// dateValue leaves the field zero for an empty value.
+func dateValue(field func(*Config) *time.Time) codec {
+	return codec{
+		parse: func(cfg *Config, value string) error {
+			if value == "" {
+				*field(cfg) = time.Time{}
+				return nil
+			}
+			parsed, err := time.Parse(dateLayout, value)
+			if err != nil {
+				return fmt.Errorf("%q is not a date, use YYYY-MM-DD", value)
+			}
+			*field(cfg) = parsed
+			return nil
+		},
+		format: func(cfg *Config) string {
+			if field(cfg).IsZero() {
+				return ""
+			}
+			return field(cfg).Format(dateLayout)
+		},
+	}
+}
+
+func locationValue(field func(*Config) **time.Location) codec {
+	return codec{
+		parse: func(cfg *Config, value string) error {
+			parsed, err := time.LoadLocation(value)
+			if err != nil {
+				return fmt.Errorf("%q is not a known time zone", value)
+			}
+			*field(cfg) = parsed
+			return nil
+		},
+		format: func(cfg *Config) string { return (*field(cfg)).String() },
+	}
+}
+
+// listValue splits value by sep, dropping empty items.
+func listValue(field func(*Config) *[]string, sep string, check func(string) error) codec {
+	return codec{
+		parse: func(cfg *Config, value string) error {
+			var items []string
+			for _, item := range strings.Split(value, sep) {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				if check != nil {
+					if err := check(item); err != nil {
+						return err
+					}
+				}
+				items = append(items, item)
+			}
+			*field(cfg) = items
+			return nil
+		},
+		format: func(cfg *Config) string { return strings.Join(*field(cfg), sep) },
+		sep:    sep,
+	}
+}
+
+func intListValue(field func(*Config) *[]int, minValue, maxValue int) codec {
+	return codec{
+		parse: func(cfg *Config, value string) error {
+			var items []int
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				parsed, err := strconv.Atoi(item)
+				if err != nil {
+					return fmt.Errorf("%q is not a whole number", item)
+				}
+				if parsed < minValue || parsed > maxValue {
+					return fmt.Errorf("%d is outside of %d-%d", parsed, minValue, maxValue)
+				}
+				items = append(items, parsed)
+			}
+			*field(cfg) = items
+			return nil
+		},
+		format: func(cfg *Config) string {
+			items := make([]string, 0, len(*field(cfg)))
+			for _, item := range *field(cfg) {
+				items = append(items, strconv.Itoa(item))
+			}
+			return strings.Join(items, ",")
+		},
+		sep: ",",
+	}
+}
+
+func notEmpty(value string) error {
+	if value == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	return nil
+}
+
+func port(value string) error {
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 || parsed > 65535 {
+		return fmt.Errorf("%q is not a port number (1-65535)", value)
+	}
+	return nil
+}
+
+// absoluteURL accepts an empty value, optional URLs are left empty.
+func absoluteURL(value string) error {
+	if value == "" {
+		return nil
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", value)
+	}
+	return nil
+}
+
+func oneOf(allowed ...string) func(string) error {
+	return func(value string) error {
+		if !slices.Contains(allowed, value) {
+			return fmt.Errorf("%q is not one of %s", value, strings.Join(allowed, ", "))
+		}
+		return nil
+	}
+}
+
+func cronExpression(value string) error {
+	if _, err := cron.ParseStandard(value); err != nil {
+		return fmt.Errorf("%q is not a cron expression: %w", value, err)
+	}
+	return nil
+}
+
+func imageVariant(value string) error {
+	width, format, _ := strings.Cut(value, ":")
+	if parsed, err := strconv.Atoi(width); err != nil || parsed <= 0 {
+		return fmt.Errorf("%q has no valid width, use WIDTH:FORMAT", value)
+	}
+	if format != "" {
+		return oneOf("jpeg", "png", "webp")(format)
+	}
+	return nil
+}
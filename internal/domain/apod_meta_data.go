@@ -1,10 +1,88 @@
 package domain
 
+const (
+	MediaTypeImage = "image"
+	MediaTypeVideo = "video"
+	MediaTypeOther = "other"
+)
+
+const (
+	AssetVariantStandard  = "standard"
+	AssetVariantHD        = "hd"
+	AssetVariantThumbnail = "thumbnail"
+)
+
 type ApodImageMetaData struct {
-	Id                    int    `json:"id" db:"id"`
-	Title                 string `json:"title" db:"title"`
-	Explanation           string `json:"explanation" db:"explanation"`
-	Date                  string `json:"date" db:"date"`
-	Copyright             string `json:"copyright" db:"copyright"`
-	LocalStorageImagePath string `json:"localImagePath" db:"local_storage_path"`
+	Id             int         `json:"id" db:"id"`
+	Title          string      `json:"title" db:"title"`
+	Explanation    string      `json:"explanation" db:"explanation"`
+	Date           string      `json:"date" db:"date"`
+	Copyright      string      `json:"copyright" db:"copyright"`
+	MediaType      string      `json:"mediaType" db:"media_type"`
+	URL            string      `json:"url" db:"url"`
+	HDURL          string      `json:"hdUrl" db:"hd_url"`
+	ThumbnailURL   string      `json:"thumbnailUrl" db:"thumbnail_url"`
+	ServiceVersion string      `json:"serviceVersion" db:"service_version"`
+	StorageKey     string      `json:"-" db:"storage_key"`
+	ImageURL       string      `json:"imageUrl,omitempty" db:"-"`
+	Assets         []ApodAsset `json:"assets" db:"-"`
+}
+
+type ApodAsset struct {
+	Id          int    `json:"id" db:"id"`
+	ImageId     int    `json:"-" db:"apod_image_id"`
+	Variant     string `json:"variant" db:"variant"`
+	SourceURL   string `json:"sourceUrl" db:"source_url"`
+	StorageKey  string `json:"-" db:"storage_key"`
+	SHA256      string `json:"sha256,omitempty" db:"sha256"`
+	Size        int64  `json:"size,omitempty" db:"size_bytes"`
+	ContentType string `json:"contentType,omitempty" db:"content_type"`
+	Width       int    `json:"width,omitempty" db:"width"`
+	Height      int    `json:"height,omitempty" db:"height"`
+	URL         string `json:"url" db:"-"`
+}
+
+// Conflict policies decide what saving an already stored date does: skip
+// keeps the stored entry, refresh overwrites its metadata.
+const (
+	ConflictPolicySkip    = "skip"
+	ConflictPolicyRefresh = "refresh"
+)
+
+const (
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
+
+type ApodImagesFilter struct {
+	MediaType string
+	From      string
+	To        string
+	Cursor    string
+	Limit     int
+	Order     string
+}
+
+type ApodImagesPage struct {
+	Images     []ApodImageMetaData
+	NextCursor string
+}
+
+type ApodSearchQuery struct {
+	Query  string
+	Cursor string
+	Limit  int
+	Offset int
+}
+
+type ApodSearchResult struct {
+	ApodImageMetaData
+	Rank           float64 `json:"rank" db:"rank"`
+	TitleHighlight string  `json:"titleHighlight" db:"title_highlight"`
+	Snippet        string  `json:"snippet" db:"snippet"`
+}
+
+type ApodSearchPage struct {
+	Results    []ApodSearchResult
+	NextCursor string
 }
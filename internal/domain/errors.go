@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned by repositories when no APOD is stored for a date.
+var ErrNotFound = errors.New("APOD not found")
+
+// ErrAlreadyExists matches every AlreadyExistsError with errors.Is.
+var ErrAlreadyExists = errors.New("APOD already exists")
+
+// AlreadyExistsError is returned when an APOD is saved for a date that is
+// already stored and the conflict policy does not allow refreshing it.
+type AlreadyExistsError struct {
+	Date string
+}
+
+func (e *AlreadyExistsError) Error() string {
+	return fmt.Sprintf("APOD for %s already exists", e.Date)
+}
+
+func (e *AlreadyExistsError) Is(target error) bool {
+	return target == ErrAlreadyExists
+}
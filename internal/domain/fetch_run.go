@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+const (
+	FetchTriggerSchedule = "schedule"
+	FetchTriggerManual   = "manual"
+	FetchTriggerBackfill = "backfill"
+)
+
+const (
+	FetchStatusSucceeded = "succeeded"
+	FetchStatusSkipped   = "skipped"
+	FetchStatusFailed    = "failed"
+)
+
+// FetchRun is one attempt of the worker to fetch and store a single APOD day.
+// TargetDate is empty when the latest APOD was requested and never arrived.
+type FetchRun struct {
+	Id              int       `json:"id" db:"id"`
+	Trigger         string    `json:"trigger" db:"trigger"`
+	Status          string    `json:"status" db:"status"`
+	TargetDate      string    `json:"targetDate,omitempty" db:"target_date"`
+	StartedAt       time.Time `json:"startedAt" db:"started_at"`
+	FinishedAt      time.Time `json:"finishedAt" db:"finished_at"`
+	HTTPStatus      int       `json:"httpStatus,omitempty" db:"http_status"`
+	BytesDownloaded int64     `json:"bytesDownloaded" db:"bytes_downloaded"`
+	Attempts        int       `json:"attempts" db:"attempts"`
+	Error           string    `json:"error,omitempty" db:"error"`
+}
+
+type FetchRunsFilter struct {
+	From    string
+	To      string
+	Status  string
+	Trigger string
+	Limit   int
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+const (
+	JobTypeFetch    = "fetch"
+	JobTypeBackfill = "backfill"
+)
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
+
+type Job struct {
+	Id         string      `json:"id"`
+	Type       string      `json:"type"`
+	Status     string      `json:"status"`
+	Date       string      `json:"date,omitempty"`
+	From       string      `json:"from,omitempty"`
+	To         string      `json:"to,omitempty"`
+	Progress   JobProgress `json:"progress"`
+	Result     string      `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	StartedAt  *time.Time  `json:"startedAt,omitempty"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+}
+
+// JobProgress counts days: Total is the size of the requested range and Done
+// how many of them were already looked at, whatever the outcome.
+type JobProgress struct {
+	Total   int `json:"total"`
+	Done    int `json:"done"`
+	Saved   int `json:"saved"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
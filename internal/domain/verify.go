@@ -0,0 +1,25 @@
+package domain
+
+const (
+	VerifyProblemMissing          = "missing"
+	VerifyProblemSizeMismatch     = "size_mismatch"
+	VerifyProblemChecksumMismatch = "checksum_mismatch"
+)
+
+type VerifyIssue struct {
+	Date       string `json:"date"`
+	Variant    string `json:"variant"`
+	StorageKey string `json:"storageKey"`
+	Problem    string `json:"problem"`
+	Expected   string `json:"expected,omitempty"`
+	Actual     string `json:"actual,omitempty"`
+}
+
+// VerifyReport is the outcome of comparing stored files with their recorded
+// checksums. Unverified counts assets stored before checksums were recorded,
+// only their presence is checked.
+type VerifyReport struct {
+	Checked    int           `json:"checked"`
+	Unverified int           `json:"unverified"`
+	Issues     []VerifyIssue `json:"issues"`
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/service"
+	"net/http"
+	"strings"
+)
+
+type APODJobsService interface {
+	EnqueueFetch(date string) (domain.Job, error)
+	EnqueueBackfill(from, to string) (domain.Job, error)
+	GetJob(id string) (domain.Job, error)
+	GetFetchRuns(ctx context.Context, filter domain.FetchRunsFilter) ([]domain.FetchRun, error)
+}
+
+// AdminHandler exposes manual fetch and backfill triggers. Every request must
+// carry the configured token as "Authorization: Bearer <token>".
+type AdminHandler struct {
+	jobs   APODJobsService
+	token  string
+	logger *zap.Logger
+}
+
+func NewAdminHandler(jobs APODJobsService, token string, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		jobs:   jobs,
+		token:  token,
+		logger: logger,
+	}
+}
+
+func (h *AdminHandler) Init(r *mux.Router) {
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(h.authenticate)
+
+	admin.HandleFunc("/fetch", h.Fetch).Methods(http.MethodPost)
+	admin.HandleFunc("/backfill", h.Backfill).Methods(http.MethodPost)
+	admin.HandleFunc("/jobs/{id}", h.GetJob).Methods(http.MethodGet)
+	admin.HandleFunc("/runs", h.GetFetchRuns).Methods(http.MethodGet)
+}
+
+func (h *AdminHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *AdminHandler) Fetch(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.EnqueueFetch(r.URL.Query().Get("date"))
+	h.writeJobAccepted(w, job, err)
+}
+
+func (h *AdminHandler) Backfill(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	job, err := h.jobs.EnqueueBackfill(query.Get("from"), query.Get("to"))
+	h.writeJobAccepted(w, job, err)
+}
+
+func (h *AdminHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.GetJob(mux.Vars(r)["id"])
+	if err != nil {
+		if errors.Is(err, service.ErrJobNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, "Job not found")
+			return
+		}
+
+		h.logger.Error("failed to get job", zap.Error(err))
+		writeErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *AdminHandler) GetFetchRuns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid limit. Use a positive number.")
+		return
+	}
+
+	runs, err := h.jobs.GetFetchRuns(r.Context(), domain.FetchRunsFilter{
+		From:    query.Get("from"),
+		To:      query.Get("to"),
+		Status:  query.Get("status"),
+		Trigger: query.Get("trigger"),
+		Limit:   limit,
+	})
+	if err != nil {
+		h.logger.Error("failed to get fetch runs", zap.Error(err))
+
+		if errors.Is(err, service.ErrInvalidDate) {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD.")
+			return
+		}
+
+		if errors.Is(err, service.ErrInvalidFetchStatus) || errors.Is(err, service.ErrInvalidFetchTrigger) || errors.Is(err, service.ErrInvalidLimit) {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		writeErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	if runs == nil {
+		runs = []domain.FetchRun{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+func (h *AdminHandler) writeJobAccepted(w http.ResponseWriter, job domain.Job, err error) {
+	if err != nil {
+		h.logger.Error("failed to enqueue job", zap.Error(err))
+
+		if errors.Is(err, service.ErrInvalidDate) {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD.")
+			return
+		}
+
+		if errors.Is(err, service.ErrMissingBackfillStart) || errors.Is(err, service.ErrInvalidBackfillRange) {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrJobQueueFull) {
+			writeErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+
+		writeErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/admin/jobs/"+job.Id)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
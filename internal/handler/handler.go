@@ -4,40 +4,93 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
+	"io"
+	"mime"
 	"nasa-apod-app/internal/domain"
 	"nasa-apod-app/internal/service"
+	"nasa-apod-app/internal/storage"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
 )
 
 type APODImagesService interface {
-	GetAllImages(ctx context.Context) ([]domain.ApodImageMetaData, error)
+	GetAllImages(ctx context.Context, filter domain.ApodImagesFilter) (*domain.ApodImagesPage, error)
 	GetImageByDate(ctx context.Context, date string) (*domain.ApodImageMetaData, error)
+	Search(ctx context.Context, search domain.ApodSearchQuery) (*domain.ApodSearchPage, error)
+	GetImageContent(ctx context.Context, date, variant string) (io.ReadSeekCloser, storage.ObjectInfo, error)
 }
 
 type APODImagesHandler struct {
-	apodService APODImagesService
-	logger      *zap.Logger
+	apodService       APODImagesService
+	publicURL         string
+	trustProxyHeaders bool
+	logger            *zap.Logger
 }
 
 func (h *APODImagesHandler) Init(r *mux.Router) {
 	r.HandleFunc("/api/apod", h.GetAllImages).Methods(http.MethodOptions, http.MethodGet)
+	r.HandleFunc("/api/apod/search", h.Search).Methods(http.MethodOptions, http.MethodGet)
 	r.HandleFunc("/api/apod/{date}", h.GetImageByDate).Methods(http.MethodOptions, http.MethodGet)
+	r.HandleFunc("/api/apod/{date}/image", h.GetImage).Methods(http.MethodOptions, http.MethodGet, http.MethodHead)
+	r.HandleFunc("/api/apod/{date}/image/hd", h.GetHDImage).Methods(http.MethodOptions, http.MethodGet, http.MethodHead)
+	r.HandleFunc("/api/apod/{date}/thumbnail", h.GetThumbnail).Methods(http.MethodOptions, http.MethodGet, http.MethodHead)
+	r.HandleFunc("/api/apod/{date}/variants/{variant}", h.GetVariant).Methods(http.MethodOptions, http.MethodGet, http.MethodHead)
 }
 
-func NewApodImagesHandler(apodService APODImagesService, logger *zap.Logger) *APODImagesHandler {
+// NewApodImagesHandler builds image links from publicURL. Without it they are
+// derived from the request, X-Forwarded-Proto and X-Forwarded-Host are only
+// honored with trustProxyHeaders because any client can send them.
+func NewApodImagesHandler(apodService APODImagesService, publicURL string, trustProxyHeaders bool, logger *zap.Logger) *APODImagesHandler {
 	return &APODImagesHandler{
-		apodService: apodService,
-		logger:      logger,
+		apodService:       apodService,
+		publicURL:         strings.TrimRight(publicURL, "/"),
+		trustProxyHeaders: trustProxyHeaders,
+		logger:            logger,
 	}
 }
 
 func (h *APODImagesHandler) GetAllImages(w http.ResponseWriter, r *http.Request) {
-	images, err := h.apodService.GetAllImages(r.Context())
+	query := r.URL.Query()
+	filter := domain.ApodImagesFilter{
+		MediaType: query.Get("media_type"),
+		From:      query.Get("from"),
+		To:        query.Get("to"),
+		Cursor:    query.Get("cursor"),
+		Order:     query.Get("order"),
+	}
+
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid limit. Use a positive number.")
+		return
+	}
+	filter.Limit = limit
+
+	page, err := h.apodService.GetAllImages(r.Context(), filter)
 	if err != nil {
 		h.logger.Error("failed to get images", zap.Error(err))
 
+		if errors.Is(err, service.ErrInvalidMediaType) {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid media type. Use image, video or other.")
+			return
+		}
+
+		if errors.Is(err, service.ErrInvalidDate) {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD.")
+			return
+		}
+
+		if errors.Is(err, service.ErrInvalidCursor) || errors.Is(err, service.ErrInvalidOrder) || errors.Is(err, service.ErrInvalidLimit) {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		if errors.Is(err, service.ErrImagesNotFound) {
 			writeErrorResponse(w, http.StatusNotFound, "Images not found")
 			return
@@ -47,10 +100,54 @@ func (h *APODImagesHandler) GetAllImages(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	images := page.Images
+	for i := range images {
+		h.setImageURLs(r, &images[i])
+	}
+
+	h.setNextPageHeaders(w, r, page.NextCursor)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(images)
 }
 
+func (h *APODImagesHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid limit. Use a positive number.")
+		return
+	}
+
+	page, err := h.apodService.Search(r.Context(), domain.ApodSearchQuery{
+		Query:  query.Get("q"),
+		Cursor: query.Get("cursor"),
+		Limit:  limit,
+	})
+	if err != nil {
+		h.logger.Error("failed to search images", zap.Error(err))
+
+		if errors.Is(err, service.ErrEmptySearchQuery) || errors.Is(err, service.ErrInvalidCursor) || errors.Is(err, service.ErrInvalidLimit) {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		writeErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	results := page.Results
+	for i := range results {
+		h.setImageURLs(r, &results[i].ApodImageMetaData)
+	}
+
+	h.setNextPageHeaders(w, r, page.NextCursor)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
 func (h *APODImagesHandler) GetImageByDate(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	date := vars["date"]
@@ -73,10 +170,135 @@ func (h *APODImagesHandler) GetImageByDate(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	h.setImageURLs(r, image)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(image)
 }
 
+func (h *APODImagesHandler) GetImage(w http.ResponseWriter, r *http.Request) {
+	h.serveImageContent(w, r, domain.AssetVariantStandard)
+}
+
+func (h *APODImagesHandler) GetHDImage(w http.ResponseWriter, r *http.Request) {
+	h.serveImageContent(w, r, domain.AssetVariantHD)
+}
+
+func (h *APODImagesHandler) GetThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.serveImageContent(w, r, domain.AssetVariantThumbnail)
+}
+
+func (h *APODImagesHandler) GetVariant(w http.ResponseWriter, r *http.Request) {
+	h.serveImageContent(w, r, mux.Vars(r)["variant"])
+}
+
+func (h *APODImagesHandler) serveImageContent(w http.ResponseWriter, r *http.Request, variant string) {
+	vars := mux.Vars(r)
+	date := vars["date"]
+
+	content, info, err := h.apodService.GetImageContent(r.Context(), date, variant)
+	if err != nil {
+		h.logger.Error("failed to get image content", zap.Error(err))
+
+		if errors.Is(err, service.ErrInvalidDate) {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD.")
+			return
+		}
+
+		if errors.Is(err, service.ErrImageNotFound) || errors.Is(err, service.ErrAssetNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, "Image not found")
+			return
+		}
+
+		writeErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	defer content.Close()
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(info.Key))
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("ETag", info.ETag)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+
+	// ServeContent takes care of Content-Length, Last-Modified, Range and conditional requests.
+	http.ServeContent(w, r, "", info.LastModified, content)
+}
+
+func (h *APODImagesHandler) setImageURLs(r *http.Request, image *domain.ApodImageMetaData) {
+	baseURL := h.baseURL(r)
+
+	for i := range image.Assets {
+		image.Assets[i].URL = baseURL + imageAssetPath(image.Date, image.Assets[i].Variant)
+		if image.Assets[i].Variant == domain.AssetVariantStandard {
+			image.ImageURL = image.Assets[i].URL
+		}
+	}
+}
+
+func (h *APODImagesHandler) setNextPageHeaders(w http.ResponseWriter, r *http.Request, cursor string) {
+	if cursor == "" {
+		return
+	}
+
+	next := r.URL.Query()
+	next.Set("cursor", cursor)
+
+	w.Header().Set("X-Next-Cursor", cursor)
+	w.Header().Set("Link", fmt.Sprintf(`<%s%s?%s>; rel="next"`, h.baseURL(r), r.URL.Path, next.Encode()))
+}
+
+func (h *APODImagesHandler) baseURL(r *http.Request) string {
+	if h.publicURL != "" {
+		return h.publicURL
+	}
+
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if h.trustProxyHeaders {
+		if proto := forwardedValue(r, "X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if forwardedHost := forwardedValue(r, "X-Forwarded-Host"); forwardedHost != "" {
+			host = forwardedHost
+		}
+	}
+
+	return scheme + "://" + host
+}
+
+// forwardedValue returns the value the proxy closest to the client set, the
+// first one of a comma separated list.
+func forwardedValue(r *http.Request, header string) string {
+	value, _, _ := strings.Cut(r.Header.Get(header), ",")
+	return strings.TrimSpace(value)
+}
+
+func imageAssetPath(date, variant string) string {
+	switch variant {
+	case domain.AssetVariantStandard:
+		return "/api/apod/" + date + "/image"
+	case domain.AssetVariantHD:
+		return "/api/apod/" + date + "/image/hd"
+	case domain.AssetVariantThumbnail:
+		return "/api/apod/" + date + "/thumbnail"
+	}
+	return "/api/apod/" + date + "/variants/" + url.PathEscape(variant)
+}
+
+func parseLimit(limit string) (int, error) {
+	if limit == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(limit)
+}
+
 func writeErrorResponse(w http.ResponseWriter, code int, message string) {
 	response := map[string]interface{}{
 		"code":    code,
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/repository/memory"
+	"nasa-apod-app/internal/service"
+	"nasa-apod-app/internal/storage/local"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTestRouter serves the handler over a memory repository and local storage
+// holding images.
+func newTestRouter(t *testing.T, images ...domain.ApodImageMetaData) (*mux.Router, *local.LocalStorage) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	repo := memory.NewMemoryRepository()
+	for _, image := range images {
+		require.NoError(t, repo.Save(context.Background(), image, domain.ConflictPolicySkip))
+	}
+	imageStorage := local.NewLocalStorage(t.TempDir())
+
+	router := mux.NewRouter()
+	apodService := service.NewApodImagesService(logger, repo, imageStorage, config.WorkerConfig{})
+	NewApodImagesHandler(apodService, "https://apod.example.com", false, logger).Init(router)
+	return router, imageStorage
+}
+
+func serve(router http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestImageContent(t *testing.T) {
+	ctx := context.Background()
+	standard := []byte("standard image bytes")
+	hd := []byte("hd image bytes, a few more of them")
+
+	router, imageStorage := newTestRouter(t, domain.ApodImageMetaData{
+		Date:      "2024-01-01",
+		MediaType: domain.MediaTypeImage,
+		Assets: []domain.ApodAsset{
+			{Variant: domain.AssetVariantStandard, StorageKey: "apod/2024-01-01.jpg", ContentType: "image/jpeg"},
+			{Variant: domain.AssetVariantHD, StorageKey: "apod/2024-01-01_hd.png", ContentType: "image/png"},
+		},
+	}, domain.ApodImageMetaData{
+		Date:      "2024-01-03",
+		MediaType: domain.MediaTypeImage,
+		Assets: []domain.ApodAsset{
+			{Variant: domain.AssetVariantStandard, StorageKey: "apod/2024-01-03.jpg", ContentType: "image/jpeg"},
+		},
+	})
+	standardInfo, err := imageStorage.Put(ctx, "apod/2024-01-01.jpg", bytes.NewReader(standard), "image/jpeg")
+	require.NoError(t, err)
+	_, err = imageStorage.Put(ctx, "apod/2024-01-01_hd.png", bytes.NewReader(hd), "image/png")
+	require.NoError(t, err)
+
+	tests := []struct {
+		path        string
+		content     []byte
+		contentType string
+	}{
+		{"/api/apod/2024-01-01/image", standard, "image/jpeg"},
+		{"/api/apod/2024-01-01/image/hd", hd, "image/png"},
+		// Image days have no thumbnail of their own, the standard image stands in.
+		{"/api/apod/2024-01-01/thumbnail", standard, "image/jpeg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := serve(router, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, strconv.Itoa(len(tt.content)), w.Header().Get("Content-Length"))
+			assert.NotEmpty(t, w.Header().Get("ETag"))
+			assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+			assert.Equal(t, tt.content, w.Body.Bytes())
+		})
+	}
+
+	t.Run("validators", func(t *testing.T) {
+		w := serve(router, httptest.NewRequest(http.MethodGet, "/api/apod/2024-01-01/image", nil))
+
+		assert.Equal(t, standardInfo.ETag, w.Header().Get("ETag"))
+		assert.Equal(t, standardInfo.LastModified.UTC().Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+	})
+
+	t.Run("range", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/apod/2024-01-01/image", nil)
+		r.Header.Set("Range", "bytes=0-7")
+		w := serve(router, r)
+
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "8", w.Header().Get("Content-Length"))
+		assert.Equal(t, "bytes 0-7/"+strconv.Itoa(len(standard)), w.Header().Get("Content-Range"))
+		assert.Equal(t, standard[:8], w.Body.Bytes())
+	})
+
+	t.Run("if-none-match", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/apod/2024-01-01/image", nil)
+		r.Header.Set("If-None-Match", standardInfo.ETag)
+		w := serve(router, r)
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.Bytes())
+	})
+
+	t.Run("if-modified-since", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/apod/2024-01-01/image", nil)
+		r.Header.Set("If-Modified-Since", standardInfo.LastModified.Add(time.Second).UTC().Format(http.TimeFormat))
+		w := serve(router, r)
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+	})
+
+	t.Run("head", func(t *testing.T) {
+		w := serve(router, httptest.NewRequest(http.MethodHead, "/api/apod/2024-01-01/image/hd", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, strconv.Itoa(len(hd)), w.Header().Get("Content-Length"))
+		assert.Empty(t, w.Body.Bytes())
+	})
+
+	for name, path := range map[string]string{
+		"missing date":       "/api/apod/2024-01-02/image",
+		"missing hd variant": "/api/apod/2024-01-03/image/hd",
+		"missing object":     "/api/apod/2024-01-03/image",
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := serve(router, httptest.NewRequest(http.MethodGet, path, nil))
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+	}
+
+	t.Run("invalid date", func(t *testing.T) {
+		w := serve(router, httptest.NewRequest(http.MethodGet, "/api/apod/yesterday/image", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestGetAllImages(t *testing.T) {
+	router, _ := newTestRouter(t,
+		domain.ApodImageMetaData{Date: "2024-01-01", MediaType: domain.MediaTypeImage},
+		domain.ApodImageMetaData{Date: "2024-01-02", MediaType: domain.MediaTypeVideo},
+		domain.ApodImageMetaData{Date: "2024-01-03", MediaType: domain.MediaTypeImage},
+	)
+
+	get := func(t *testing.T, target string) (*httptest.ResponseRecorder, []string) {
+		t.Helper()
+
+		w := serve(router, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusOK {
+			return w, nil
+		}
+
+		var images []domain.ApodImageMetaData
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&images))
+
+		dates := make([]string, 0, len(images))
+		for _, image := range images {
+			dates = append(dates, image.Date)
+		}
+		return w, dates
+	}
+
+	t.Run("walks pages with the next cursor", func(t *testing.T) {
+		w, dates := get(t, "/api/apod?limit=2")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"2024-01-03", "2024-01-02"}, dates)
+		assert.Equal(t, "2024-01-02", w.Header().Get("X-Next-Cursor"))
+
+		link := w.Header().Get("Link")
+		assert.True(t, strings.HasPrefix(link, "<https://apod.example.com/api/apod?"), link)
+		assert.True(t, strings.HasSuffix(link, `>; rel="next"`), link)
+
+		next, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`))
+		require.NoError(t, err)
+		assert.Equal(t, "2", next.Query().Get("limit"))
+		assert.Equal(t, "2024-01-02", next.Query().Get("cursor"))
+
+		w, dates = get(t, next.RequestURI())
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"2024-01-01"}, dates)
+		assert.Empty(t, w.Header().Get("X-Next-Cursor"))
+		assert.Empty(t, w.Header().Get("Link"))
+	})
+
+	t.Run("ascending order", func(t *testing.T) {
+		w, dates := get(t, "/api/apod?order=asc&limit=2")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"2024-01-01", "2024-01-02"}, dates)
+		assert.Equal(t, "2024-01-02", w.Header().Get("X-Next-Cursor"))
+
+		w, dates = get(t, "/api/apod?order=asc&limit=2&cursor="+w.Header().Get("X-Next-Cursor"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"2024-01-03"}, dates)
+	})
+
+	t.Run("filters by media type", func(t *testing.T) {
+		w, dates := get(t, "/api/apod?media_type=video")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"2024-01-02"}, dates)
+	})
+
+	for name, target := range map[string]string{
+		"non-numeric limit": "/api/apod?limit=abc",
+		"negative limit":    "/api/apod?limit=-1",
+		"invalid from":      "/api/apod?from=yesterday",
+		"invalid to":        "/api/apod?to=2024-13-01",
+		"invalid order":     "/api/apod?order=sideways",
+	} {
+		t.Run(name, func(t *testing.T) {
+			w, _ := get(t, target)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	tests := []struct {
+		name              string
+		publicURL         string
+		trustProxyHeaders bool
+		want              string
+	}{
+		{"public URL wins", "https://apod.example.com/", true, "https://apod.example.com"},
+		{"forwarded headers ignored by default", "", false, "http://internal:8080"},
+		{"forwarded headers from a trusted proxy", "", true, "https://apod.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewApodImagesHandler(nil, tt.publicURL, tt.trustProxyHeaders, zap.NewNop())
+
+			r := httptest.NewRequest("GET", "/api/apod", nil)
+			r.Host = "internal:8080"
+			r.Header.Set("X-Forwarded-Proto", "https")
+			r.Header.Set("X-Forwarded-Host", "apod.example.com, evil.example.com")
+
+			assert.Equal(t, tt.want, h.baseURL(r))
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"nasa-apod-app/internal/domain"
+	"net/http"
+)
+
+type ReadinessService interface {
+	Ready(ctx context.Context) domain.HealthReport
+}
+
+// HealthHandler serves the liveness and readiness probes. Liveness only tells
+// that the process serves HTTP, readiness runs the dependency checks.
+type HealthHandler struct {
+	readiness ReadinessService
+}
+
+func NewHealthHandler(readiness ReadinessService) *HealthHandler {
+	return &HealthHandler{
+		readiness: readiness,
+	}
+}
+
+func (h *HealthHandler) Init(r *mux.Router) {
+	r.HandleFunc("/healthz", h.Healthz).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", h.Readyz).Methods(http.MethodGet)
+}
+
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": domain.HealthStatusOK})
+}
+
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := h.readiness.Ready(r.Context())
+
+	code := http.StatusOK
+	if report.Status != domain.HealthStatusOK {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
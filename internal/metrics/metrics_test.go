@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"nasa-apod-app/internal/storage"
+	"nasa-apod-app/internal/storage/local"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestInstrument(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/apod/{date}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["date"] == "missing" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}).Methods(http.MethodGet)
+	handler := Instrument(router, router)
+
+	// The counters are global, only what the requests add is compared so the
+	// test can run repeatedly.
+	ok := HTTPRequests.WithLabelValues("/api/apod/{date}", http.MethodGet, "200")
+	notFound := HTTPRequests.WithLabelValues("/api/apod/{date}", http.MethodGet, "404")
+	unmatched := HTTPRequests.WithLabelValues("unmatched", http.MethodGet, "404")
+	okBefore, notFoundBefore, unmatchedBefore := testutil.ToFloat64(ok), testutil.ToFloat64(notFound), testutil.ToFloat64(unmatched)
+
+	for _, path := range []string{"/api/apod/2024-01-01", "/api/apod/2024-01-02", "/api/apod/missing", "/nope"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(ok)-okBefore)
+	assert.Equal(t, 1.0, testutil.ToFloat64(notFound)-notFoundBefore)
+	assert.Equal(t, 1.0, testutil.ToFloat64(unmatched)-unmatchedBefore)
+}
+
+func TestObserveQuery(t *testing.T) {
+	before := sampleCount(t, DBQueryDuration.WithLabelValues("test_query"))
+
+	ObserveQuery("test_query", time.Now())
+	ObserveQuery("test_query", time.Now())
+
+	assert.Equal(t, before+2, sampleCount(t, DBQueryDuration.WithLabelValues("test_query")))
+}
+
+func TestStorageUsageCollector(t *testing.T) {
+	ctx := context.Background()
+	imageStorage := local.NewLocalStorage(t.TempDir())
+	_, err := imageStorage.Put(ctx, "apod/2024-01-01.jpg", bytes.NewReader(make([]byte, 100)), "image/jpeg")
+	assert.NoError(t, err)
+	_, err = imageStorage.Put(ctx, "apod/2024-01-01_hd.jpg", bytes.NewReader(make([]byte, 250)), "image/jpeg")
+	assert.NoError(t, err)
+	_, err = imageStorage.Put(ctx, storage.ReservedPrefix+"readyz-probe", bytes.NewReader(make([]byte, 2)), "text/plain")
+	assert.NoError(t, err)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&storageUsageCollector{storage: imageStorage, logger: zap.NewNop()})
+
+	expected := `
+# HELP apod_storage_objects Number of stored objects.
+# TYPE apod_storage_objects gauge
+apod_storage_objects 2
+# HELP apod_storage_usage_bytes Total size of all stored objects.
+# TYPE apod_storage_usage_bytes gauge
+apod_storage_usage_bytes 350
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected)))
+}
+
+// sampleCount returns how many observations the histogram holds.
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	t.Helper()
+
+	var metric dto.Metric
+	assert.NoError(t, observer.(prometheus.Metric).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
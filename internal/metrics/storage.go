@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"context"
+	"nasa-apod-app/internal/storage"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	storageUsageTTL     = time.Minute
+	storageUsageTimeout = 10 * time.Second
+)
+
+var (
+	storageUsageBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "storage", "usage_bytes"),
+		"Total size of all stored objects.", nil, nil,
+	)
+	storageObjectsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "storage", "objects"),
+		"Number of stored objects.", nil, nil,
+	)
+)
+
+// storageUsageCollector lists the storage when scraped. Listing a bucket is
+// not free, so the totals are reused for storageUsageTTL.
+type storageUsageCollector struct {
+	storage storage.Storage
+	logger  *zap.Logger
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	bytes     int64
+	objects   int
+}
+
+// RegisterStorageUsage exposes the size of imageStorage on the default registry.
+func RegisterStorageUsage(imageStorage storage.Storage, logger *zap.Logger) error {
+	return prometheus.Register(&storageUsageCollector{storage: imageStorage, logger: logger})
+}
+
+func (c *storageUsageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- storageUsageBytesDesc
+	ch <- storageObjectsDesc
+}
+
+func (c *storageUsageCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checkedAt) > storageUsageTTL {
+		ctx, cancel := context.WithTimeout(context.Background(), storageUsageTimeout)
+		defer cancel()
+
+		objects, err := c.storage.List(ctx, "")
+		if err != nil {
+			c.logger.Warn("Failed to list storage for usage metrics", zap.Error(err))
+			ch <- prometheus.NewInvalidMetric(storageUsageBytesDesc, err)
+			return
+		}
+
+		c.bytes, c.objects = 0, 0
+		for _, object := range objects {
+			if strings.HasPrefix(object.Key, storage.ReservedPrefix) {
+				continue
+			}
+			c.bytes += object.Size
+			c.objects++
+		}
+		c.checkedAt = time.Now()
+	}
+
+	ch <- prometheus.MustNewConstMetric(storageUsageBytesDesc, prometheus.GaugeValue, float64(c.bytes))
+	ch <- prometheus.MustNewConstMetric(storageObjectsDesc, prometheus.GaugeValue, float64(c.objects))
+}
@@ -1,32 +1,205 @@
 package migration
 
 import (
+	"context"
 	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
 )
 
-//go:embed migrations/*.sql
+//go:embed migrations/*.sql migrations/sqlite/*.sql
 var embedMigrations embed.FS
 
+var ErrNothingToRollBack = fmt.Errorf("no applied migration to roll back")
+
+// Migratory applies the embedded migrations of one database backend. On
+// PostgreSQL every operation holds an advisory lock while it runs, so replicas
+// starting at the same time migrate one after another instead of racing each
+// other.
 type Migratory struct {
+	dialect goose.Dialect
+	dir     string
 }
 
+// NewMigration returns the PostgreSQL migrations.
 func NewMigration() *Migratory {
-	return &Migratory{}
+	return &Migratory{dialect: goose.DialectPostgres, dir: "migrations"}
+}
+
+// NewSQLiteMigration returns the SQLite migrations. SQLite locks the database
+// file itself and only serves a single node, so they run without a lock.
+func NewSQLiteMigration() *Migratory {
+	return &Migratory{dialect: goose.DialectSQLite3, dir: "migrations/sqlite"}
+}
+
+// Status describes one embedded migration and whether the database has it.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Up applies every pending migration.
+func (m *Migratory) Up(ctx context.Context, db *sqlx.DB) error {
+	provider, err := m.provider(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = provider.Up(ctx)
+	return err
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migratory) Down(ctx context.Context, db *sqlx.DB) error {
+	provider, err := m.provider(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = provider.Down(ctx)
+	if errors.Is(err, goose.ErrNoNextVersion) {
+		return ErrNothingToRollBack
+	}
+	return err
+}
+
+// Redo rolls back the most recently applied migration and applies it again.
+// Both steps run under one lock, so no replica migrates in between.
+func (m *Migratory) Redo(ctx context.Context, db *sqlx.DB) error {
+	return m.withLock(ctx, db, func(provider *goose.Provider) error {
+		_, err := provider.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			return ErrNothingToRollBack
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = provider.UpByOne(ctx)
+		return err
+	})
 }
 
-func (m *Migratory) Migrate(db *sqlx.DB) error {
+// Status lists every embedded migration in version order.
+func (m *Migratory) Status(ctx context.Context, db *sqlx.DB) ([]Status, error) {
+	provider, err := m.provider(db)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := provider.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, migration := range migrations {
+		statuses = append(statuses, Status{
+			Version:   migration.Source.Version,
+			Name:      migration.Source.Path,
+			Applied:   migration.State == goose.StateApplied,
+			AppliedAt: migration.AppliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Version is the version of the newest migration applied to the database, 0
+// for an empty database.
+func (m *Migratory) Version(ctx context.Context, db *sqlx.DB) (int64, error) {
+	provider, err := m.provider(db)
+	if err != nil {
+		return 0, err
+	}
+	return provider.GetDBVersion(ctx)
+}
+
+// LatestVersion is the version of the newest embedded migration, the version a
+// fully migrated database reports.
+func (m *Migratory) LatestVersion() (int64, error) {
 	goose.SetBaseFS(embedMigrations)
 
-	if err := goose.SetDialect("postgres"); err != nil {
+	migrations, err := goose.CollectMigrations(m.dir, 0, goose.MaxVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	latest, err := migrations.Last()
+	if err != nil {
+		return 0, err
+	}
+	return latest.Version, nil
+}
+
+// provider must not be closed, closing it closes db.
+func (m *Migratory) provider(db *sqlx.DB) (*goose.Provider, error) {
+	var options []goose.ProviderOption
+	if m.dialect == goose.DialectPostgres {
+		locker, err := newLocker()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, goose.WithSessionLocker(locker))
+	}
+	return m.newProvider(db, options...)
+}
+
+// withLock holds the migration lock on a dedicated connection while fn runs
+// several operations on a provider that does not lock on its own.
+func (m *Migratory) withLock(ctx context.Context, db *sqlx.DB, fn func(provider *goose.Provider) error) error {
+	provider, err := m.newProvider(db)
+	if err != nil {
 		return err
 	}
+	if m.dialect != goose.DialectPostgres {
+		return fn(provider)
+	}
 
-	if err := goose.Up(db.DB, "migrations"); err != nil {
+	locker, err := newLocker()
+	if err != nil {
 		return err
 	}
 
-	return nil
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection for migration lock: %w", err)
+	}
+	defer conn.Close()
+
+	if err := locker.SessionLock(ctx, conn); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer locker.SessionUnlock(context.WithoutCancel(ctx), conn)
+
+	return fn(provider)
+}
+
+// newLocker waits up to 5 minutes for another replica to finish migrating.
+func newLocker() (lock.SessionLocker, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration lock: %w", err)
+	}
+	return locker, nil
+}
+
+func (m *Migratory) newProvider(db *sqlx.DB, options ...goose.ProviderOption) (*goose.Provider, error) {
+	migrations, err := fs.Sub(embedMigrations, m.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := goose.NewProvider(m.dialect, db.DB, migrations, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	return provider, nil
 }
@@ -0,0 +1,170 @@
+package migration
+
+import (
+	"context"
+	"io/fs"
+	"nasa-apod-app/internal/repository/repotest"
+	"nasa-apod-app/internal/repository/sqlite"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestEveryMigrationHasDownScript(t *testing.T) {
+	files, err := fs.Glob(embedMigrations, "migrations/*.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	sqliteFiles, err := fs.Glob(embedMigrations, "migrations/sqlite/*.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, sqliteFiles)
+	files = append(files, sqliteFiles...)
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			content, err := fs.ReadFile(embedMigrations, file)
+			require.NoError(t, err)
+
+			_, down, found := strings.Cut(string(content), "-- +goose Down")
+			require.True(t, found, "missing -- +goose Down")
+
+			var statements []string
+			for _, line := range strings.Split(down, "\n") {
+				if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+					statements = append(statements, line)
+				}
+			}
+			assert.NotEmpty(t, statements, "down script is empty")
+		})
+	}
+}
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := sqlite.Open(filepath.Join(t.TempDir(), "apod.db"), zap.NewNop())
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		testRoundTrip(t, NewSQLiteMigration(), db, `
+			SELECT name FROM sqlite_master
+			WHERE type = 'table' AND name <> 'goose_db_version' AND name NOT LIKE 'sqlite_%'`)
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		testRoundTrip(t, NewMigration(), repotest.PostgresDB(t), `
+			SELECT table_name FROM information_schema.tables
+			WHERE table_schema = current_schema() AND table_name <> 'goose_db_version'`)
+	})
+}
+
+// testRoundTrip applies, redoes and rolls back every migration. tablesQuery
+// lists the tables besides goose's own.
+func testRoundTrip(t *testing.T, migrator *Migratory, db *sqlx.DB, tablesQuery string) {
+	ctx := context.Background()
+
+	provider, err := migrator.provider(db)
+	require.NoError(t, err)
+	sources := provider.ListSources()
+
+	// Every down script must leave the schema in a state its up script can be
+	// applied to again.
+	for _, source := range sources {
+		_, err := provider.UpByOne(ctx)
+		require.NoError(t, err, "up %s", source.Path)
+		require.NoError(t, migrator.Redo(ctx, db), "redo %s", source.Path)
+	}
+
+	latest, err := migrator.LatestVersion()
+	require.NoError(t, err)
+	version, err := migrator.Version(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+
+	for range sources {
+		require.NoError(t, migrator.Down(ctx, db))
+	}
+	assert.ErrorIs(t, migrator.Down(ctx, db), ErrNothingToRollBack)
+	assert.ErrorIs(t, migrator.Redo(ctx, db), ErrNothingToRollBack)
+
+	var tables []string
+	require.NoError(t, db.SelectContext(ctx, &tables, tablesQuery))
+	assert.Empty(t, tables, "rolling back every migration leaves tables behind")
+
+	require.NoError(t, migrator.Up(ctx, db))
+	statuses, err := migrator.Status(ctx, db)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(sources))
+	for _, status := range statuses {
+		assert.True(t, status.Applied, status.Name)
+	}
+}
+
+func TestStorageKeyMigrationRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db := repotest.PostgresDB(t)
+
+	provider, err := NewMigration().provider(db)
+	require.NoError(t, err)
+
+	// A row as the service stored it before storage keys, 003 copies its path
+	// into the standard asset.
+	_, err = provider.UpTo(ctx, 2)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO apod_images (title, explanation, date, copyright, local_storage_path)
+		VALUES ('Title', 'Explanation', '2024-01-01', '', './storage/apod/2024-01-01.jpg')`)
+	require.NoError(t, err)
+	_, err = provider.UpTo(ctx, 3)
+	require.NoError(t, err)
+
+	paths := func(column string) []string {
+		var paths []string
+		require.NoError(t, db.SelectContext(ctx, &paths, `
+			SELECT `+column+` FROM apod_images
+			UNION ALL
+			SELECT `+column+` FROM apod_assets`))
+		return paths
+	}
+
+	_, err = provider.UpTo(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"apod/2024-01-01.jpg", "apod/2024-01-01.jpg"}, paths("storage_key"))
+
+	_, err = provider.DownTo(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"./storage/apod/2024-01-01.jpg", "./storage/apod/2024-01-01.jpg"}, paths("local_storage_path"))
+}
+
+func TestConcurrentUp(t *testing.T) {
+	ctx := context.Background()
+	db := repotest.PostgresDB(t)
+	migrator := NewMigration()
+
+	// Without the advisory lock the replicas would race to create the same
+	// tables and all but one would fail.
+	errs := make([]error, 3)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = migrator.Up(ctx, db)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	latest, err := migrator.LatestVersion()
+	require.NoError(t, err)
+	version, err := migrator.Version(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+}
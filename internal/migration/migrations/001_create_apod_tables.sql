@@ -13,6 +13,6 @@ CREATE TABLE apod_images (
 
 -- +goose Down
 -- +goose StatementBegin
-DROP TABLE IF EXISTS stores;
+DROP TABLE IF EXISTS apod_images
 -- +goose StatementEnd
 
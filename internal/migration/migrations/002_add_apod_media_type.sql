@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE apod_images
+    ADD COLUMN media_type TEXT NOT NULL DEFAULT 'image',
+    ADD COLUMN url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN thumbnail_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN service_version TEXT NOT NULL DEFAULT ''
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX apod_images_media_type_idx ON apod_images (media_type)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS apod_images_media_type_idx
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE apod_images
+    DROP COLUMN IF EXISTS media_type,
+    DROP COLUMN IF EXISTS url,
+    DROP COLUMN IF EXISTS thumbnail_url,
+    DROP COLUMN IF EXISTS service_version
+-- +goose StatementEnd
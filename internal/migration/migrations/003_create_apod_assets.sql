@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE apod_images ADD COLUMN hd_url TEXT NOT NULL DEFAULT ''
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE apod_assets (
+ id SERIAL PRIMARY KEY,
+ apod_image_id INTEGER NOT NULL REFERENCES apod_images (id) ON DELETE CASCADE,
+ variant TEXT NOT NULL,
+ source_url TEXT NOT NULL,
+ local_storage_path TEXT NOT NULL,
+ created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ UNIQUE (apod_image_id, variant)
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO apod_assets (apod_image_id, variant, source_url, local_storage_path)
+SELECT id, 'standard', url, local_storage_path
+FROM apod_images
+WHERE local_storage_path <> ''
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS apod_assets
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE apod_images DROP COLUMN IF EXISTS hd_url
+-- +goose StatementEnd
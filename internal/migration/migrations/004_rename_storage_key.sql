@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE apod_images RENAME COLUMN local_storage_path TO storage_key
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE apod_assets RENAME COLUMN local_storage_path TO storage_key
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE apod_images SET storage_key = regexp_replace(storage_key, '^(\./)?storage/', '')
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE apod_assets SET storage_key = regexp_replace(storage_key, '^(\./)?storage/', '')
+-- +goose StatementEnd
+
+-- +goose Down
+-- Paths go back to the ./storage/apod/<date>.jpg form the service wrote before.
+-- +goose StatementBegin
+UPDATE apod_assets SET storage_key = './storage/' || storage_key WHERE storage_key <> ''
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE apod_images SET storage_key = './storage/' || storage_key WHERE storage_key <> ''
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE apod_assets RENAME COLUMN storage_key TO local_storage_path
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE apod_images RENAME COLUMN storage_key TO local_storage_path
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE apod_images ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(explanation, '')), 'B')
+) STORED
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX apod_images_search_idx ON apod_images USING GIN (search_vector)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS apod_images_search_idx
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE apod_images DROP COLUMN IF EXISTS search_vector
+-- +goose StatementEnd
@@ -0,0 +1,77 @@
+-- +goose Up
+-- Every row after the first one of a date is a duplicate, the first one is kept.
+-- +goose StatementBegin
+CREATE TEMPORARY TABLE duplicate_apod_images AS
+SELECT duplicate.id, duplicate.storage_key,
+       (SELECT MIN(original.id) FROM apod_images original WHERE original.date = duplicate.date) AS original_id
+FROM apod_images duplicate
+WHERE EXISTS (SELECT 1 FROM apod_images original WHERE original.date = duplicate.date AND original.id < duplicate.id)
+-- +goose StatementEnd
+
+-- Variants the kept row lacks are moved over from the first duplicate that has them.
+-- +goose StatementBegin
+UPDATE apod_assets asset
+SET apod_image_id = duplicate.original_id
+FROM duplicate_apod_images duplicate
+WHERE asset.apod_image_id = duplicate.id
+  AND NOT EXISTS (
+      SELECT 1 FROM apod_assets kept
+      WHERE kept.apod_image_id = duplicate.original_id AND kept.variant = asset.variant)
+  AND asset.id = (
+      SELECT MIN(other.id)
+      FROM apod_assets other
+      JOIN duplicate_apod_images other_duplicate ON other_duplicate.id = other.apod_image_id
+      WHERE other_duplicate.original_id = duplicate.original_id AND other.variant = asset.variant)
+-- +goose StatementEnd
+
+-- Stored files only the deleted duplicates point to are listed for cleanup.
+-- +goose StatementBegin
+CREATE TABLE apod_orphaned_objects (
+ storage_key TEXT PRIMARY KEY,
+ found_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO apod_orphaned_objects (storage_key)
+SELECT storage_key FROM duplicate_apod_images WHERE storage_key <> ''
+UNION
+SELECT asset.storage_key
+FROM apod_assets asset
+JOIN duplicate_apod_images duplicate ON duplicate.id = asset.apod_image_id
+WHERE asset.storage_key <> ''
+EXCEPT
+SELECT storage_key FROM apod_images WHERE id NOT IN (SELECT id FROM duplicate_apod_images)
+EXCEPT
+SELECT storage_key FROM apod_assets WHERE apod_image_id NOT IN (SELECT id FROM duplicate_apod_images)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DELETE FROM apod_images WHERE id IN (SELECT id FROM duplicate_apod_images)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE duplicate_apod_images
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS apod_images_date_idx
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE apod_images ADD CONSTRAINT apod_images_date_key UNIQUE (date)
+-- +goose StatementEnd
+
+-- +goose Down
+-- Duplicates removed on the way up are not restored.
+-- +goose StatementBegin
+DROP TABLE IF EXISTS apod_orphaned_objects
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE apod_images DROP CONSTRAINT IF EXISTS apod_images_date_key
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX apod_images_date_idx ON apod_images (date)
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE apod_assets
+ ADD COLUMN sha256 TEXT NOT NULL DEFAULT '',
+ ADD COLUMN size_bytes BIGINT NOT NULL DEFAULT 0,
+ ADD COLUMN content_type TEXT NOT NULL DEFAULT '',
+ ADD COLUMN width INTEGER NOT NULL DEFAULT 0,
+ ADD COLUMN height INTEGER NOT NULL DEFAULT 0
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE apod_assets
+ DROP COLUMN IF EXISTS sha256,
+ DROP COLUMN IF EXISTS size_bytes,
+ DROP COLUMN IF EXISTS content_type,
+ DROP COLUMN IF EXISTS width,
+ DROP COLUMN IF EXISTS height
+-- +goose StatementEnd
@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE apod_images (
+ id INTEGER PRIMARY KEY AUTOINCREMENT,
+ title TEXT NOT NULL,
+ explanation TEXT NOT NULL,
+ date TEXT NOT NULL UNIQUE,
+ copyright TEXT NOT NULL,
+ storage_key TEXT NOT NULL DEFAULT '',
+ media_type TEXT NOT NULL DEFAULT 'image',
+ url TEXT NOT NULL DEFAULT '',
+ hd_url TEXT NOT NULL DEFAULT '',
+ thumbnail_url TEXT NOT NULL DEFAULT '',
+ service_version TEXT NOT NULL DEFAULT '',
+ created_at TEXT DEFAULT CURRENT_TIMESTAMP
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX apod_images_media_type_idx ON apod_images (media_type)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE apod_assets (
+ id INTEGER PRIMARY KEY AUTOINCREMENT,
+ apod_image_id INTEGER NOT NULL REFERENCES apod_images (id) ON DELETE CASCADE,
+ variant TEXT NOT NULL,
+ source_url TEXT NOT NULL,
+ storage_key TEXT NOT NULL,
+ sha256 TEXT NOT NULL DEFAULT '',
+ size_bytes INTEGER NOT NULL DEFAULT 0,
+ content_type TEXT NOT NULL DEFAULT '',
+ width INTEGER NOT NULL DEFAULT 0,
+ height INTEGER NOT NULL DEFAULT 0,
+ created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+ UNIQUE (apod_image_id, variant)
+)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS apod_assets
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS apod_images
+-- +goose StatementEnd
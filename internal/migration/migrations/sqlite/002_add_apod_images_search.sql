@@ -0,0 +1,53 @@
+-- +goose Up
+-- The full text index mirrors apod_images through triggers, titles weigh more
+-- than explanations when ranking.
+-- +goose StatementBegin
+CREATE VIRTUAL TABLE apod_images_search USING fts5 (
+ title,
+ explanation,
+ content = 'apod_images',
+ content_rowid = 'id',
+ tokenize = 'porter unicode61'
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO apod_images_search (rowid, title, explanation)
+SELECT id, title, explanation FROM apod_images
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER apod_images_search_insert AFTER INSERT ON apod_images BEGIN
+ INSERT INTO apod_images_search (rowid, title, explanation) VALUES (new.id, new.title, new.explanation);
+END
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER apod_images_search_delete AFTER DELETE ON apod_images BEGIN
+ INSERT INTO apod_images_search (apod_images_search, rowid, title, explanation) VALUES ('delete', old.id, old.title, old.explanation);
+END
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER apod_images_search_update AFTER UPDATE OF title, explanation ON apod_images BEGIN
+ INSERT INTO apod_images_search (apod_images_search, rowid, title, explanation) VALUES ('delete', old.id, old.title, old.explanation);
+ INSERT INTO apod_images_search (rowid, title, explanation) VALUES (new.id, new.title, new.explanation);
+END
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS apod_images_search_update
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS apod_images_search_delete
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS apod_images_search_insert
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS apod_images_search
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE fetch_runs (
+ id INTEGER PRIMARY KEY AUTOINCREMENT,
+ "trigger" TEXT NOT NULL,
+ status TEXT NOT NULL,
+ target_date TEXT,
+ started_at TIMESTAMP NOT NULL,
+ finished_at TIMESTAMP NOT NULL,
+ http_status INTEGER NOT NULL DEFAULT 0,
+ bytes_downloaded INTEGER NOT NULL DEFAULT 0,
+ attempts INTEGER NOT NULL DEFAULT 0,
+ error TEXT NOT NULL DEFAULT ''
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX fetch_runs_started_at_idx ON fetch_runs (started_at)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX fetch_runs_target_date_idx ON fetch_runs (target_date)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS fetch_runs
+-- +goose StatementEnd
@@ -1,9 +1,13 @@
 package models
 
 type APODResponse struct {
-	Title       string `json:"title"`
-	Explanation string `json:"explanation"`
-	Date        string `json:"date"`
-	Copyright   string `json:"copyright"`
-	URL         string `json:"url"`
+	Title          string `json:"title"`
+	Explanation    string `json:"explanation"`
+	Date           string `json:"date"`
+	Copyright      string `json:"copyright"`
+	URL            string `json:"url"`
+	HDURL          string `json:"hdurl"`
+	MediaType      string `json:"media_type"`
+	ThumbnailURL   string `json:"thumbnail_url"`
+	ServiceVersion string `json:"service_version"`
 }
@@ -1,33 +1,103 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
+	"io"
 	"nasa-apod-app/internal/config"
 	"nasa-apod-app/internal/migration"
 	"nasa-apod-app/internal/repository/postgres"
+	"nasa-apod-app/internal/repository/sqlite"
+	"nasa-apod-app/internal/service"
 	"time"
 )
 
-func InitDB(cfg config.DBConfig, migrator *migration.Migratory, logger *zap.Logger) (repo *postgres.ApodImagesRepository, err error) {
-	logger.Info("Got db config")
+const (
+	BackendPostgres = "postgres"
+	BackendSQLite   = "sqlite"
+)
 
-	var db *sqlx.DB
-	for i := 0; i < cfg.ReconnRetry; i++ {
+// Repository is implemented by every database backend.
+type Repository interface {
+	service.ApodImagesRepo
+	service.FetchRunsRepo
+	service.DatabaseHealth
+	io.Closer
+}
 
-		db, err = postgres.ConnectToPostgresDB(cfg, logger)
-		if err == nil {
-			logger.Info("Db migration")
+// NewMigration returns the migrations of the configured backend.
+func NewMigration(cfg config.DBConfig) *migration.Migratory {
+	if cfg.Backend == BackendSQLite {
+		return migration.NewSQLiteMigration()
+	}
+	return migration.NewMigration()
+}
 
-			if err = migrator.Migrate(db); err != nil {
-				return nil, fmt.Errorf("migration failure: %w", err)
-			}
+// InitDB connects to the configured database and, unless auto-migration is
+// disabled, applies pending migrations.
+func InitDB(cfg config.DBConfig, migrator *migration.Migratory, logger *zap.Logger) (Repository, error) {
+	db, err := Connect(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
 
-			repo = postgres.NewPostgresRepository(db)
-			logger.Info("Migrations done")
+	if err := migrate(context.Background(), cfg, db, migrator, logger); err != nil {
+		db.Close()
+		return nil, err
+	}
 
-			return repo, nil
+	if cfg.Backend == BackendSQLite {
+		return sqlite.NewSQLiteRepository(db), nil
+	}
+	return postgres.NewPostgresRepository(db), nil
+}
+
+func migrate(ctx context.Context, cfg config.DBConfig, db *sqlx.DB, migrator *migration.Migratory, logger *zap.Logger) error {
+	if cfg.AutoMigrate {
+		logger.Info("Db migration")
+		if err := migrator.Up(ctx, db); err != nil {
+			return fmt.Errorf("migration failure: %w", err)
+		}
+		logger.Info("Migrations done")
+		return nil
+	}
+
+	// Migrations are left to a separate job, only point out a database that
+	// is behind. /readyz fails until it catches up.
+	version, err := migrator.Version(ctx, db)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	latest, err := migrator.LatestVersion()
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+	if version < latest {
+		logger.Warn("Database has pending migrations and auto-migration is disabled", zap.Int64("version", version), zap.Int64("latest", latest))
+	}
+	return nil
+}
+
+// Connect opens the configured database without applying migrations. A
+// PostgreSQL server is retried while it is not reachable yet.
+func Connect(cfg config.DBConfig, logger *zap.Logger) (db *sqlx.DB, err error) {
+	logger.Info("Got db config")
+
+	if cfg.Backend == BackendSQLite {
+		db, err = sqlite.Open(cfg.SQLitePath, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+		}
+		return db, nil
+	}
+
+	for i := 0; i < cfg.ReconnRetry; i++ {
+		db, err = postgres.ConnectToPostgresDB(cfg, logger)
+		if err == nil {
+			logger.Info("Successfully connected to postgres")
+			return db, nil
 		}
 
 		logger.With(
@@ -38,6 +108,5 @@ func InitDB(cfg config.DBConfig, migrator *migration.Migratory, logger *zap.Logg
 		time.Sleep(cfg.TimeWaitPerTry)
 	}
 
-	logger.Info("Successfully connected to postgres")
-	return repo, err
+	return nil, fmt.Errorf("failed to connect to database: %w", err)
 }
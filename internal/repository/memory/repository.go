@@ -0,0 +1,259 @@
+// Package memory keeps APOD metadata and fetch runs in process memory. It
+// passes the same conformance suite as the database backends, which makes it
+// a faithful stand-in in tests.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"nasa-apod-app/internal/domain"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type ApodImagesRepository struct {
+	mu          sync.RWMutex
+	images      map[string]domain.ApodImageMetaData
+	runs        []domain.FetchRun
+	lastId      int
+	lastAssetId int
+}
+
+func NewMemoryRepository() *ApodImagesRepository {
+	return &ApodImagesRepository{
+		images: make(map[string]domain.ApodImageMetaData),
+	}
+}
+
+// Save follows the database backends: a refresh keeps the stored id, falls
+// back to the stored storage key when the new one is empty and keeps stored
+// assets of variants that are not saved again.
+func (r *ApodImagesRepository) Save(ctx context.Context, metadata domain.ApodImageMetaData, onConflict string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to save APOD data: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	metadata.Assets = cloneAssets(metadata.Assets)
+
+	stored, exists := r.images[metadata.Date]
+	if exists {
+		if onConflict != domain.ConflictPolicyRefresh {
+			return &domain.AlreadyExistsError{Date: metadata.Date}
+		}
+
+		metadata.Id = stored.Id
+		if metadata.StorageKey == "" {
+			metadata.StorageKey = stored.StorageKey
+		}
+	} else {
+		r.lastId++
+		metadata.Id = r.lastId
+	}
+
+	for i := range metadata.Assets {
+		metadata.Assets[i].ImageId = metadata.Id
+		if previous, ok := findAsset(stored.Assets, metadata.Assets[i].Variant); ok {
+			metadata.Assets[i].Id = previous.Id
+		} else {
+			r.lastAssetId++
+			metadata.Assets[i].Id = r.lastAssetId
+		}
+	}
+	for _, asset := range stored.Assets {
+		if _, ok := findAsset(metadata.Assets, asset.Variant); !ok {
+			metadata.Assets = append(metadata.Assets, asset)
+		}
+	}
+	sort.Slice(metadata.Assets, func(i, j int) bool {
+		return metadata.Assets[i].Id < metadata.Assets[j].Id
+	})
+
+	r.images[metadata.Date] = metadata
+	return nil
+}
+
+func (r *ApodImagesRepository) GetImageByDate(ctx context.Context, date string) (*domain.ApodImageMetaData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get APOD image by date: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	image, exists := r.images[date]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", domain.ErrNotFound, date)
+	}
+	image.Assets = cloneAssets(image.Assets)
+	return &image, nil
+}
+
+func (r *ApodImagesRepository) GetAllImages(ctx context.Context, filter domain.ApodImagesFilter) ([]domain.ApodImageMetaData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get all APOD images: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var images []domain.ApodImageMetaData
+	for _, image := range r.images {
+		if filter.MediaType != "" && image.MediaType != filter.MediaType {
+			continue
+		}
+		if (filter.From != "" && image.Date < filter.From) || (filter.To != "" && image.Date > filter.To) {
+			continue
+		}
+		if filter.Cursor != "" {
+			if filter.Order == domain.SortOrderAsc && image.Date <= filter.Cursor {
+				continue
+			}
+			if filter.Order != domain.SortOrderAsc && image.Date >= filter.Cursor {
+				continue
+			}
+		}
+		image.Assets = cloneAssets(image.Assets)
+		images = append(images, image)
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		if filter.Order == domain.SortOrderAsc {
+			return images[i].Date < images[j].Date
+		}
+		return images[i].Date > images[j].Date
+	})
+
+	if filter.Limit > 0 && len(images) > filter.Limit {
+		images = images[:filter.Limit]
+	}
+	return images, nil
+}
+
+// Search counts case-insensitive occurrences of the query terms. Title
+// matches weigh ten times as much as matches in the explanation, the same
+// weighting the database backends use.
+func (r *ApodImagesRepository) Search(ctx context.Context, search domain.ApodSearchQuery) ([]domain.ApodSearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search APOD images: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	terms := strings.Fields(strings.ToLower(search.Query))
+
+	var results []domain.ApodSearchResult
+	for _, image := range r.images {
+		title := strings.ToLower(image.Title)
+		explanation := strings.ToLower(image.Explanation)
+
+		var rank float64
+		for _, term := range terms {
+			rank += 10*float64(strings.Count(title, term)) + float64(strings.Count(explanation, term))
+		}
+		if rank == 0 {
+			continue
+		}
+
+		image.Assets = cloneAssets(image.Assets)
+		results = append(results, domain.ApodSearchResult{ApodImageMetaData: image, Rank: rank, TitleHighlight: image.Title, Snippet: image.Explanation})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Date > results[j].Date
+	})
+
+	if search.Offset >= len(results) {
+		return nil, nil
+	}
+	results = results[search.Offset:]
+	if search.Limit > 0 && len(results) > search.Limit {
+		results = results[:search.Limit]
+	}
+	return results, nil
+}
+
+func (r *ApodImagesRepository) ExistsByDate(ctx context.Context, date string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, exists := r.images[date]
+	return exists, nil
+}
+
+func (r *ApodImagesRepository) SaveFetchRun(ctx context.Context, run domain.FetchRun) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to save fetch run: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run.Id = len(r.runs) + 1
+	r.runs = append(r.runs, run)
+	return nil
+}
+
+func (r *ApodImagesRepository) GetFetchRuns(ctx context.Context, filter domain.FetchRunsFilter) ([]domain.FetchRun, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get fetch runs: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var runs []domain.FetchRun
+	for _, run := range r.runs {
+		// Runs without a target date never match a date range, like NULL
+		// in SQL.
+		if filter.From != "" && (run.TargetDate == "" || run.TargetDate < filter.From) {
+			continue
+		}
+		if filter.To != "" && (run.TargetDate == "" || run.TargetDate > filter.To) {
+			continue
+		}
+		if filter.Status != "" && run.Status != filter.Status {
+			continue
+		}
+		if filter.Trigger != "" && run.Trigger != filter.Trigger {
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].StartedAt.Equal(runs[j].StartedAt) {
+			return runs[i].StartedAt.After(runs[j].StartedAt)
+		}
+		return runs[i].Id > runs[j].Id
+	})
+
+	if filter.Limit > 0 && len(runs) > filter.Limit {
+		runs = runs[:filter.Limit]
+	}
+	return runs, nil
+}
+
+func cloneAssets(assets []domain.ApodAsset) []domain.ApodAsset {
+	return append([]domain.ApodAsset{}, assets...)
+}
+
+func findAsset(assets []domain.ApodAsset, variant string) (domain.ApodAsset, bool) {
+	for _, asset := range assets {
+		if asset.Variant == variant {
+			return asset, true
+		}
+	}
+	return domain.ApodAsset{}, false
+}
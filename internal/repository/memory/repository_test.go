@@ -0,0 +1,19 @@
+package memory
+
+import (
+	"nasa-apod-app/internal/repository/repotest"
+	"nasa-apod-app/internal/service"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) service.ApodImagesRepo {
+		return NewMemoryRepository()
+	})
+}
+
+func TestFetchRunsConformance(t *testing.T) {
+	repotest.RunFetchRuns(t, func(t *testing.T) service.FetchRunsRepo {
+		return NewMemoryRepository()
+	})
+}
@@ -1,26 +1,26 @@
 package postgres
 
 import (
-	"fmt"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 	"nasa-apod-app/internal/config"
+	"net"
+	"net/url"
 )
 
 func ConnectToPostgresDB(cfg config.DBConfig, logger *zap.Logger) (*sqlx.DB, error) {
-	connStr := fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		cfg.Username,
-		cfg.Password,
-		cfg.Host,
-		cfg.Port,
-		cfg.DBName,
-	)
+	connURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.Username, cfg.Password.Value()),
+		Host:     net.JoinHostPort(cfg.Host, cfg.Port),
+		Path:     cfg.DBName,
+		RawQuery: "sslmode=disable",
+	}
 
-	logger.Info("connection string :" + connStr)
-	db, err := sqlx.Open("postgres", connStr)
+	logger.Info("Connecting to postgres", zap.String("url", connURL.Redacted()))
+	db, err := sqlx.Open("postgres", connURL.String())
 	if err != nil {
 		return nil, err
 	}
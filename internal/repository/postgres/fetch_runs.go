@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/repository/sqlbackend"
+)
+
+func (r *ApodImagesRepository) SaveFetchRun(ctx context.Context, run domain.FetchRun) (err error) {
+	ctx, finish := backend.StartQuery(ctx, "save_fetch_run")
+	defer func() { finish(err) }()
+
+	query := `
+       INSERT INTO fetch_runs (trigger, status, target_date, started_at, finished_at, http_status, bytes_downloaded, attempts, error)
+       VALUES ($1, $2, NULLIF($3, '')::date, $4, $5, $6, $7, $8, $9)
+   `
+
+	_, err = r.db.ExecContext(ctx, query,
+		run.Trigger,
+		run.Status,
+		run.TargetDate,
+		run.StartedAt,
+		run.FinishedAt,
+		run.HTTPStatus,
+		run.BytesDownloaded,
+		run.Attempts,
+		run.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save fetch run: %w", err)
+	}
+	return nil
+}
+
+func (r *ApodImagesRepository) GetFetchRuns(ctx context.Context, filter domain.FetchRunsFilter) (runs []domain.FetchRun, err error) {
+	ctx, finish := backend.StartQuery(ctx, "get_fetch_runs")
+	defer func() { finish(err) }()
+
+	query := `
+       SELECT id, trigger, status, COALESCE(TO_CHAR(target_date, 'YYYY-MM-DD'), '') AS target_date, started_at, finished_at, http_status, bytes_downloaded, attempts, error
+       FROM fetch_runs
+   `
+
+	query, args := sqlbackend.FetchRunsQuery(query, filter)
+	err = r.db.SelectContext(ctx, &runs, r.db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fetch runs: %w", err)
+	}
+
+	return runs, nil
+}
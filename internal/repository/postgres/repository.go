@@ -2,12 +2,18 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"github.com/pressly/goose/v3"
 	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/repository/sqlbackend"
 )
 
+var backend = sqlbackend.Backend{Name: "postgres", System: "postgresql"}
+
 type ApodImagesRepository struct {
 	db *sqlx.DB
 }
@@ -19,57 +25,230 @@ func NewPostgresRepository(db *sqlx.DB) *ApodImagesRepository {
 	return repo
 }
 
-func (r *ApodImagesRepository) Save(metadata domain.ApodImageMetaData) error {
+func (r *ApodImagesRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *ApodImagesRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// SchemaVersion returns the version of the newest migration applied to the database.
+func (r *ApodImagesRepository) SchemaVersion(ctx context.Context) (int64, error) {
+	version, err := goose.GetDBVersionContext(ctx, r.db.DB)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Save inserts metadata together with its assets. When the date is already
+// stored, onConflict decides between returning a domain.AlreadyExistsError
+// (skip) and overwriting the stored metadata (refresh).
+func (r *ApodImagesRepository) Save(ctx context.Context, metadata domain.ApodImageMetaData, onConflict string) (err error) {
+	ctx, finish := backend.StartQuery(ctx, "save")
+	defer func() { finish(err) }()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	conflictClause := "ON CONFLICT (date) DO NOTHING"
+	if onConflict == domain.ConflictPolicyRefresh {
+		conflictClause = `ON CONFLICT (date) DO UPDATE SET
+           title = EXCLUDED.title,
+           explanation = EXCLUDED.explanation,
+           storage_key = COALESCE(NULLIF(EXCLUDED.storage_key, ''), apod_images.storage_key),
+           copyright = EXCLUDED.copyright,
+           media_type = EXCLUDED.media_type,
+           url = EXCLUDED.url,
+           hd_url = EXCLUDED.hd_url,
+           thumbnail_url = EXCLUDED.thumbnail_url,
+           service_version = EXCLUDED.service_version`
+	}
+
 	query := `
-       INSERT INTO apod_images (title, explanation, date, local_storage_path, copyright)
-       VALUES ($1, $2, $3, $4, $5)
+       INSERT INTO apod_images (title, explanation, date, storage_key, copyright, media_type, url, hd_url, thumbnail_url, service_version)
+       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+       ` + conflictClause + `
+       RETURNING id
    `
-	_, err := r.db.Exec(query, metadata.Title, metadata.Explanation, metadata.Date, metadata.LocalStorageImagePath, metadata.Copyright)
+	var imageId int
+	err = tx.GetContext(ctx, &imageId, query,
+		metadata.Title,
+		metadata.Explanation,
+		metadata.Date,
+		metadata.StorageKey,
+		metadata.Copyright,
+		metadata.MediaType,
+		metadata.URL,
+		metadata.HDURL,
+		metadata.ThumbnailURL,
+		metadata.ServiceVersion,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &domain.AlreadyExistsError{Date: metadata.Date}
+	}
 	if err != nil {
 		return fmt.Errorf("failed to save APOD data: %w", err)
 	}
+
+	assetQuery := `
+       INSERT INTO apod_assets (apod_image_id, variant, source_url, storage_key, sha256, size_bytes, content_type, width, height)
+       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+       ON CONFLICT (apod_image_id, variant) DO UPDATE SET
+           source_url = EXCLUDED.source_url,
+           storage_key = EXCLUDED.storage_key,
+           sha256 = EXCLUDED.sha256,
+           size_bytes = EXCLUDED.size_bytes,
+           content_type = EXCLUDED.content_type,
+           width = EXCLUDED.width,
+           height = EXCLUDED.height
+   `
+	for _, asset := range metadata.Assets {
+		_, err = tx.ExecContext(ctx, assetQuery, imageId, asset.Variant, asset.SourceURL, asset.StorageKey, asset.SHA256, asset.Size, asset.ContentType, asset.Width, asset.Height)
+		if err != nil {
+			return fmt.Errorf("failed to save APOD %s asset: %w", asset.Variant, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit APOD data: %w", err)
+	}
 	return nil
 }
 
-func (r *ApodImagesRepository) GetImageByDate(ctx context.Context, date string) (*domain.ApodImageMetaData, error) {
+func (r *ApodImagesRepository) GetImageByDate(ctx context.Context, date string) (image *domain.ApodImageMetaData, err error) {
+	ctx, finish := backend.StartQuery(ctx, "get_image_by_date")
+	defer func() { finish(err) }()
+
 	query := `
-       SELECT id, title, explanation, date, local_storage_path, copyright
+       SELECT id, title, explanation, TO_CHAR(date, 'YYYY-MM-DD') AS date, storage_key, copyright, media_type, url, hd_url, thumbnail_url, service_version
        FROM apod_images
        WHERE date = $1
    `
 
 	var imageMeta domain.ApodImageMetaData
-	err := r.db.GetContext(ctx, &imageMeta, query, date)
+	err = r.db.GetContext(ctx, &imageMeta, query, date)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrNotFound, date)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get APOD image by date: %w", err)
 	}
 
-	return &imageMeta, nil
+	images := []domain.ApodImageMetaData{imageMeta}
+	if err = r.attachAssets(ctx, images); err != nil {
+		return nil, err
+	}
+
+	return &images[0], nil
 }
 
-func (r *ApodImagesRepository) GetAllImages(ctx context.Context) ([]domain.ApodImageMetaData, error) {
+func (r *ApodImagesRepository) GetAllImages(ctx context.Context, filter domain.ApodImagesFilter) (images []domain.ApodImageMetaData, err error) {
+	ctx, finish := backend.StartQuery(ctx, "get_all_images")
+	defer func() { finish(err) }()
+
 	query := `
-       SELECT id, title, explanation, date, local_storage_path, copyright
+       SELECT id, title, explanation, TO_CHAR(date, 'YYYY-MM-DD') AS date, storage_key, copyright, media_type, url, hd_url, thumbnail_url, service_version
        FROM apod_images
    `
 
-	var images []domain.ApodImageMetaData
-	err := r.db.SelectContext(ctx, &images, query)
+	query, args := sqlbackend.ImagesQuery(query, filter)
+	err = r.db.SelectContext(ctx, &images, r.db.Rebind(query), args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all APOD images: %w", err)
 	}
 
+	if err = r.attachAssets(ctx, images); err != nil {
+		return nil, err
+	}
+
 	return images, nil
 }
 
-func (r *ApodImagesRepository) ExistsByDate(date string) (bool, error) {
+func (r *ApodImagesRepository) Search(ctx context.Context, search domain.ApodSearchQuery) (results []domain.ApodSearchResult, err error) {
+	ctx, finish := backend.StartQuery(ctx, "search")
+	defer func() { finish(err) }()
+
+	query := `
+       SELECT id, title, explanation, TO_CHAR(date, 'YYYY-MM-DD') AS date, storage_key, copyright, media_type, url, hd_url, thumbnail_url, service_version,
+              ts_rank(search_vector, query) AS rank,
+              ts_headline('english', title, query, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>') AS title_highlight,
+              ts_headline('english', explanation, query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10') AS snippet
+       FROM apod_images, websearch_to_tsquery('english', $1) AS query
+       WHERE search_vector @@ query
+       ORDER BY rank DESC, date DESC
+       LIMIT $2 OFFSET $3
+   `
+
+	err = r.db.SelectContext(ctx, &results, query, search.Query, search.Limit, search.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search APOD images: %w", err)
+	}
+
+	images := make([]domain.ApodImageMetaData, len(results))
+	for i := range results {
+		images[i] = results[i].ApodImageMetaData
+	}
+
+	if err = r.attachAssets(ctx, images); err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].Assets = images[i].Assets
+	}
+
+	return results, nil
+}
+
+func (r *ApodImagesRepository) ExistsByDate(ctx context.Context, date string) (exists bool, err error) {
+	ctx, finish := backend.StartQuery(ctx, "exists_by_date")
+	defer func() { finish(err) }()
+
 	var count int
 	query := "SELECT COUNT(1) FROM apod_images WHERE date = $1"
 
-	err := r.db.Get(&count, query, date)
+	err = r.db.GetContext(ctx, &count, query, date)
 	if err != nil {
 		return false, err
 	}
 
 	return count > 0, nil
 }
+
+func (r *ApodImagesRepository) attachAssets(ctx context.Context, images []domain.ApodImageMetaData) error {
+	if len(images) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(images))
+	byId := make(map[int]int, len(images))
+	for i := range images {
+		ids = append(ids, int64(images[i].Id))
+		byId[images[i].Id] = i
+		images[i].Assets = []domain.ApodAsset{}
+	}
+
+	query := `
+       SELECT id, apod_image_id, variant, source_url, storage_key, sha256, size_bytes, content_type, width, height
+       FROM apod_assets
+       WHERE apod_image_id = ANY($1)
+       ORDER BY id
+   `
+
+	var assets []domain.ApodAsset
+	err := r.db.SelectContext(ctx, &assets, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to get APOD assets: %w", err)
+	}
+
+	for _, asset := range assets {
+		i := byId[asset.ImageId]
+		images[i].Assets = append(images[i].Assets, asset)
+	}
+	return nil
+}
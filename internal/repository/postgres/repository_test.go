@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"context"
+	"nasa-apod-app/internal/migration"
+	"nasa-apod-app/internal/repository/repotest"
+	"nasa-apod-app/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepository(t *testing.T) *ApodImagesRepository {
+	t.Helper()
+
+	db := repotest.PostgresDB(t)
+	require.NoError(t, migration.NewMigration().Up(context.Background(), db))
+	return NewPostgresRepository(db)
+}
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) service.ApodImagesRepo {
+		return newTestRepository(t)
+	})
+}
+
+func TestFetchRunsConformance(t *testing.T) {
+	repotest.RunFetchRuns(t, func(t *testing.T) service.FetchRunsRepo {
+		return newTestRepository(t)
+	})
+}
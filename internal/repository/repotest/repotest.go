@@ -0,0 +1,333 @@
+// Package repotest is the conformance suite every repository backend runs in
+// its tests, so the backends stay interchangeable behind the service.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run checks a service.ApodImagesRepo. newRepo must return an empty
+// repository on every call.
+func Run(t *testing.T, newRepo func(t *testing.T) service.ApodImagesRepo) {
+	ctx := context.Background()
+
+	t.Run("save and get by date", func(t *testing.T) {
+		repo := newRepo(t)
+		image := testImage("2024-01-02", domain.MediaTypeImage)
+		image.Assets = []domain.ApodAsset{
+			{Variant: domain.AssetVariantStandard, SourceURL: "https://example.com/a.jpg", StorageKey: "apod/2024-01-02.jpg", SHA256: "abc", Size: 42, ContentType: "image/jpeg", Width: 4, Height: 3},
+			{Variant: domain.AssetVariantHD, SourceURL: "https://example.com/a-hd.jpg", StorageKey: "apod/2024-01-02-hd.jpg"},
+		}
+		require.NoError(t, repo.Save(ctx, image, domain.ConflictPolicySkip))
+
+		stored, err := repo.GetImageByDate(ctx, "2024-01-02")
+		require.NoError(t, err)
+		assert.NotZero(t, stored.Id)
+		assert.Equal(t, image.Title, stored.Title)
+		assert.Equal(t, image.Explanation, stored.Explanation)
+		assert.Equal(t, "2024-01-02", stored.Date)
+		assert.Equal(t, image.Copyright, stored.Copyright)
+		assert.Equal(t, image.MediaType, stored.MediaType)
+		assert.Equal(t, image.URL, stored.URL)
+		assert.Equal(t, image.HDURL, stored.HDURL)
+		assert.Equal(t, image.ThumbnailURL, stored.ThumbnailURL)
+		assert.Equal(t, image.ServiceVersion, stored.ServiceVersion)
+		assert.Equal(t, image.StorageKey, stored.StorageKey)
+
+		require.Len(t, stored.Assets, 2)
+		assert.Equal(t, assetFields(image.Assets), assetFields(stored.Assets))
+
+		exists, err := repo.ExistsByDate(ctx, "2024-01-02")
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		exists, err = repo.ExistsByDate(ctx, "2024-01-03")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("missing date", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.GetImageByDate(ctx, "2024-01-02")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+
+		images, err := repo.GetAllImages(ctx, domain.ApodImagesFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, images)
+
+		results, err := repo.Search(ctx, domain.ApodSearchQuery{Query: "title", Limit: 10})
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("one entry per date", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.Save(ctx, testImage("2024-01-02", domain.MediaTypeImage), domain.ConflictPolicySkip))
+		assert.ErrorIs(t, repo.Save(ctx, testImage("2024-01-02", domain.MediaTypeImage), domain.ConflictPolicySkip), domain.ErrAlreadyExists)
+		require.NoError(t, repo.Save(ctx, testImage("2024-01-02", domain.MediaTypeVideo), domain.ConflictPolicyRefresh))
+
+		images, err := repo.GetAllImages(ctx, domain.ApodImagesFilter{})
+		require.NoError(t, err)
+		require.Len(t, images, 1)
+		assert.Equal(t, domain.MediaTypeVideo, images[0].MediaType)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.Save(ctx, testImage("2024-01-01", domain.MediaTypeImage), domain.ConflictPolicySkip))
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := repo.Save(canceled, testImage("2024-01-02", domain.MediaTypeImage), domain.ConflictPolicySkip)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = repo.GetImageByDate(canceled, "2024-01-01")
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = repo.GetAllImages(canceled, domain.ApodImagesFilter{})
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = repo.Search(canceled, domain.ApodSearchQuery{Query: "title", Limit: 10})
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = repo.ExistsByDate(canceled, "2024-01-01")
+		assert.ErrorIs(t, err, context.Canceled)
+
+		exists, err := repo.ExistsByDate(ctx, "2024-01-02")
+		require.NoError(t, err)
+		assert.False(t, exists, "a canceled save stores nothing")
+	})
+
+	t.Run("skip keeps the stored entry", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.Save(ctx, testImage("2024-01-02", domain.MediaTypeImage), domain.ConflictPolicySkip))
+
+		changed := testImage("2024-01-02", domain.MediaTypeImage)
+		changed.Title = "Changed"
+		err := repo.Save(ctx, changed, domain.ConflictPolicySkip)
+		assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+
+		var exists *domain.AlreadyExistsError
+		if assert.True(t, errors.As(err, &exists)) {
+			assert.Equal(t, "2024-01-02", exists.Date)
+		}
+
+		stored, err := repo.GetImageByDate(ctx, "2024-01-02")
+		require.NoError(t, err)
+		assert.Equal(t, "Title 2024-01-02", stored.Title)
+	})
+
+	t.Run("refresh overwrites metadata and keeps assets", func(t *testing.T) {
+		repo := newRepo(t)
+		image := testImage("2024-01-02", domain.MediaTypeImage)
+		image.Assets = []domain.ApodAsset{
+			{Variant: domain.AssetVariantStandard, SourceURL: "https://example.com/a.jpg", StorageKey: "apod/2024-01-02.jpg"},
+			{Variant: domain.AssetVariantHD, SourceURL: "https://example.com/a-hd.jpg", StorageKey: "apod/2024-01-02-hd.jpg"},
+		}
+		require.NoError(t, repo.Save(ctx, image, domain.ConflictPolicySkip))
+		original, err := repo.GetImageByDate(ctx, "2024-01-02")
+		require.NoError(t, err)
+
+		refreshed := testImage("2024-01-02", domain.MediaTypeImage)
+		refreshed.Title = "Refreshed"
+		refreshed.StorageKey = ""
+		refreshed.Assets = []domain.ApodAsset{
+			{Variant: domain.AssetVariantStandard, SourceURL: "https://example.com/b.jpg", StorageKey: "apod/2024-01-02-b.jpg"},
+		}
+		require.NoError(t, repo.Save(ctx, refreshed, domain.ConflictPolicyRefresh))
+
+		stored, err := repo.GetImageByDate(ctx, "2024-01-02")
+		require.NoError(t, err)
+		assert.Equal(t, original.Id, stored.Id)
+		assert.Equal(t, "Refreshed", stored.Title)
+		assert.Equal(t, image.StorageKey, stored.StorageKey, "an empty storage key keeps the stored one")
+
+		variants := make(map[string]string)
+		for _, asset := range stored.Assets {
+			variants[asset.Variant] = asset.StorageKey
+		}
+		assert.Equal(t, map[string]string{
+			domain.AssetVariantStandard: "apod/2024-01-02-b.jpg",
+			domain.AssetVariantHD:       "apod/2024-01-02-hd.jpg",
+		}, variants)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, image := range []domain.ApodImageMetaData{
+			testImage("2024-01-03", domain.MediaTypeVideo),
+			testImage("2024-01-01", domain.MediaTypeImage),
+			testImage("2024-01-04", domain.MediaTypeImage),
+			testImage("2024-01-02", domain.MediaTypeImage),
+		} {
+			require.NoError(t, repo.Save(ctx, image, domain.ConflictPolicySkip))
+		}
+
+		tests := []struct {
+			name   string
+			filter domain.ApodImagesFilter
+			want   []string
+		}{
+			{"newest first by default", domain.ApodImagesFilter{}, []string{"2024-01-04", "2024-01-03", "2024-01-02", "2024-01-01"}},
+			{"oldest first", domain.ApodImagesFilter{Order: domain.SortOrderAsc}, []string{"2024-01-01", "2024-01-02", "2024-01-03", "2024-01-04"}},
+			{"date range", domain.ApodImagesFilter{From: "2024-01-02", To: "2024-01-03"}, []string{"2024-01-03", "2024-01-02"}},
+			{"media type", domain.ApodImagesFilter{MediaType: domain.MediaTypeVideo}, []string{"2024-01-03"}},
+			{"limit", domain.ApodImagesFilter{Limit: 2}, []string{"2024-01-04", "2024-01-03"}},
+			{"cursor", domain.ApodImagesFilter{Cursor: "2024-01-03", Limit: 1}, []string{"2024-01-02"}},
+			{"cursor oldest first", domain.ApodImagesFilter{Cursor: "2024-01-02", Order: domain.SortOrderAsc}, []string{"2024-01-03", "2024-01-04"}},
+			{"nothing matches", domain.ApodImagesFilter{From: "2025-01-01"}, nil},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				images, err := repo.GetAllImages(ctx, tt.filter)
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, dates(images))
+			})
+		}
+	})
+
+	t.Run("search", func(t *testing.T) {
+		repo := newRepo(t)
+
+		inTitle := testImage("2024-01-01", domain.MediaTypeImage)
+		inTitle.Title = "Andromeda Galaxy"
+		inTitle.Explanation = "A spiral seen from a dark site."
+		inTitle.Assets = []domain.ApodAsset{{Variant: domain.AssetVariantStandard, SourceURL: "https://example.com/m31.jpg", StorageKey: "apod/2024-01-01.jpg"}}
+
+		inExplanation := testImage("2024-01-02", domain.MediaTypeImage)
+		inExplanation.Title = "Autumn Sky"
+		inExplanation.Explanation = "The Andromeda galaxy rises over the hills."
+
+		unrelated := testImage("2024-01-03", domain.MediaTypeImage)
+		unrelated.Title = "Solar Prominence"
+		unrelated.Explanation = "Plasma loops above the sun."
+
+		for _, image := range []domain.ApodImageMetaData{inTitle, inExplanation, unrelated} {
+			require.NoError(t, repo.Save(ctx, image, domain.ConflictPolicySkip))
+		}
+
+		results, err := repo.Search(ctx, domain.ApodSearchQuery{Query: "andromeda", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, "2024-01-01", results[0].Date, "title matches rank first")
+		assert.Equal(t, "2024-01-02", results[1].Date)
+		assert.Greater(t, results[0].Rank, results[1].Rank)
+		assert.Len(t, results[0].Assets, 1)
+
+		results, err = repo.Search(ctx, domain.ApodSearchQuery{Query: "andromeda", Limit: 1, Offset: 1})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "2024-01-02", results[0].Date)
+
+		results, err = repo.Search(ctx, domain.ApodSearchQuery{Query: "nebula", Limit: 10})
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+}
+
+// RunFetchRuns checks a service.FetchRunsRepo. newRepo must return an empty
+// repository on every call.
+func RunFetchRuns(t *testing.T, newRepo func(t *testing.T) service.FetchRunsRepo) {
+	ctx := context.Background()
+	startedAt := time.Date(2024, 1, 5, 3, 0, 0, 0, time.UTC)
+
+	repo := newRepo(t)
+	runs := []domain.FetchRun{
+		{Trigger: domain.FetchTriggerSchedule, Status: domain.FetchStatusSucceeded, TargetDate: "2024-01-03", StartedAt: startedAt, FinishedAt: startedAt.Add(2 * time.Second), HTTPStatus: 200, BytesDownloaded: 1024, Attempts: 1},
+		{Trigger: domain.FetchTriggerManual, Status: domain.FetchStatusFailed, StartedAt: startedAt.Add(time.Hour), FinishedAt: startedAt.Add(time.Hour + time.Second), HTTPStatus: 503, Attempts: 5, Error: "unavailable"},
+		{Trigger: domain.FetchTriggerBackfill, Status: domain.FetchStatusSkipped, TargetDate: "2024-01-01", StartedAt: startedAt.Add(-time.Hour), FinishedAt: startedAt.Add(-time.Hour)},
+	}
+	for _, run := range runs {
+		require.NoError(t, repo.SaveFetchRun(ctx, run))
+	}
+
+	t.Run("newest first", func(t *testing.T) {
+		stored, err := repo.GetFetchRuns(ctx, domain.FetchRunsFilter{})
+		require.NoError(t, err)
+		require.Len(t, stored, 3)
+
+		assert.Equal(t, domain.FetchTriggerManual, stored[0].Trigger)
+		assert.Equal(t, domain.FetchTriggerSchedule, stored[1].Trigger)
+		assert.Equal(t, domain.FetchTriggerBackfill, stored[2].Trigger)
+
+		assert.NotZero(t, stored[0].Id)
+		assert.Empty(t, stored[0].TargetDate)
+		assert.Equal(t, "unavailable", stored[0].Error)
+		assert.Equal(t, 5, stored[0].Attempts)
+
+		assert.Equal(t, "2024-01-03", stored[1].TargetDate)
+		assert.True(t, runs[0].StartedAt.Equal(stored[1].StartedAt), "started at %s", stored[1].StartedAt)
+		assert.True(t, runs[0].FinishedAt.Equal(stored[1].FinishedAt), "finished at %s", stored[1].FinishedAt)
+		assert.Equal(t, 200, stored[1].HTTPStatus)
+		assert.Equal(t, int64(1024), stored[1].BytesDownloaded)
+	})
+
+	tests := []struct {
+		name   string
+		filter domain.FetchRunsFilter
+		want   []string
+	}{
+		{"status", domain.FetchRunsFilter{Status: domain.FetchStatusFailed}, []string{domain.FetchTriggerManual}},
+		{"trigger", domain.FetchRunsFilter{Trigger: domain.FetchTriggerBackfill}, []string{domain.FetchTriggerBackfill}},
+		{"target date range", domain.FetchRunsFilter{From: "2024-01-02", To: "2024-01-04"}, []string{domain.FetchTriggerSchedule}},
+		{"limit", domain.FetchRunsFilter{Limit: 1}, []string{domain.FetchTriggerManual}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored, err := repo.GetFetchRuns(ctx, tt.filter)
+			require.NoError(t, err)
+
+			var triggers []string
+			for _, run := range stored {
+				triggers = append(triggers, run.Trigger)
+			}
+			assert.Equal(t, tt.want, triggers)
+		})
+	}
+}
+
+func testImage(date, mediaType string) domain.ApodImageMetaData {
+	return domain.ApodImageMetaData{
+		Title:          "Title " + date,
+		Explanation:    "Explanation " + date,
+		Date:           date,
+		Copyright:      "Someone",
+		MediaType:      mediaType,
+		URL:            "https://example.com/" + date,
+		HDURL:          "https://example.com/" + date + "/hd",
+		ThumbnailURL:   "https://example.com/" + date + "/thumbnail",
+		ServiceVersion: "v1",
+		StorageKey:     "apod/" + date + ".jpg",
+	}
+}
+
+func dates(images []domain.ApodImageMetaData) []string {
+	var result []string
+	for _, image := range images {
+		result = append(result, image.Date)
+	}
+	return result
+}
+
+// assetFields drops the ids the repository assigns.
+func assetFields(assets []domain.ApodAsset) []domain.ApodAsset {
+	result := make([]domain.ApodAsset, 0, len(assets))
+	for _, asset := range assets {
+		asset.Id = 0
+		asset.ImageId = 0
+		result = append(result, asset)
+	}
+	return result
+}
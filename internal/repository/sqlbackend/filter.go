@@ -0,0 +1,88 @@
+package sqlbackend
+
+import (
+	"nasa-apod-app/internal/domain"
+	"strings"
+)
+
+// The clauses below use ? placeholders, pass the finished query through
+// sqlx.DB.Rebind for databases with another bind style.
+
+type conditions struct {
+	clauses []string
+	args    []interface{}
+}
+
+func (c *conditions) where(clause string, arg interface{}) {
+	c.clauses = append(c.clauses, clause)
+	c.args = append(c.args, arg)
+}
+
+func (c *conditions) String() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+// ImagesQuery appends the conditions, order and limit of filter to a select
+// from apod_images. The cursor is the date of the last image already seen.
+func ImagesQuery(query string, filter domain.ApodImagesFilter) (string, []interface{}) {
+	var c conditions
+
+	if filter.MediaType != "" {
+		c.where("media_type = ?", filter.MediaType)
+	}
+	if filter.From != "" {
+		c.where("date >= ?", filter.From)
+	}
+	if filter.To != "" {
+		c.where("date <= ?", filter.To)
+	}
+
+	order := "DESC"
+	if filter.Order == domain.SortOrderAsc {
+		order = "ASC"
+	}
+
+	if filter.Cursor != "" {
+		if order == "ASC" {
+			c.where("date > ?", filter.Cursor)
+		} else {
+			c.where("date < ?", filter.Cursor)
+		}
+	}
+
+	query += c.String() + " ORDER BY date " + order
+	return withLimit(query, c.args, filter.Limit)
+}
+
+// FetchRunsQuery appends the conditions, order and limit of filter to a
+// select from fetch_runs, newest runs first.
+func FetchRunsQuery(query string, filter domain.FetchRunsFilter) (string, []interface{}) {
+	var c conditions
+
+	if filter.From != "" {
+		c.where("target_date >= ?", filter.From)
+	}
+	if filter.To != "" {
+		c.where("target_date <= ?", filter.To)
+	}
+	if filter.Status != "" {
+		c.where("status = ?", filter.Status)
+	}
+	if filter.Trigger != "" {
+		c.where(`"trigger" = ?`, filter.Trigger)
+	}
+
+	query += c.String() + " ORDER BY started_at DESC, id DESC"
+	return withLimit(query, c.args, filter.Limit)
+}
+
+func withLimit(query string, args []interface{}, limit int) (string, []interface{}) {
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	return query, args
+}
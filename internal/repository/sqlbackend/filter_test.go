@@ -0,0 +1,33 @@
+package sqlbackend
+
+import (
+	"nasa-apod-app/internal/domain"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImagesQuery(t *testing.T) {
+	query, args := ImagesQuery("SELECT id FROM apod_images", domain.ApodImagesFilter{
+		MediaType: domain.MediaTypeImage,
+		From:      "2024-01-01",
+		Cursor:    "2024-01-10",
+		Order:     domain.SortOrderAsc,
+		Limit:     5,
+	})
+
+	assert.Equal(t, "SELECT id FROM apod_images WHERE media_type = ? AND date >= ? AND date > ? ORDER BY date ASC LIMIT ?", query)
+	assert.Equal(t, []interface{}{domain.MediaTypeImage, "2024-01-01", "2024-01-10", 5}, args)
+	assert.Equal(t, "SELECT id FROM apod_images WHERE media_type = $1 AND date >= $2 AND date > $3 ORDER BY date ASC LIMIT $4", sqlx.Rebind(sqlx.DOLLAR, query))
+}
+
+func TestFetchRunsQuery(t *testing.T) {
+	query, args := FetchRunsQuery("SELECT id FROM fetch_runs", domain.FetchRunsFilter{})
+	assert.Equal(t, "SELECT id FROM fetch_runs ORDER BY started_at DESC, id DESC", query)
+	assert.Empty(t, args)
+
+	query, args = FetchRunsQuery("SELECT id FROM fetch_runs", domain.FetchRunsFilter{To: "2024-01-31", Status: "failed", Trigger: "manual", Limit: 10})
+	assert.Equal(t, `SELECT id FROM fetch_runs WHERE target_date <= ? AND status = ? AND "trigger" = ? ORDER BY started_at DESC, id DESC LIMIT ?`, query)
+	assert.Equal(t, []interface{}{"2024-01-31", "failed", "manual", 10}, args)
+}
@@ -0,0 +1,55 @@
+// Package sqlbackend holds what the PostgreSQL and SQLite repositories share:
+// query spans and metrics and the filter clauses of list queries. Only the SQL
+// that differs between the two databases stays in their packages.
+package sqlbackend
+
+import (
+	"context"
+	"errors"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/metrics"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Backend names a database in spans, e.g. Backend{Name: "postgres", System:
+// "postgresql"} starts spans named postgres.save.
+type Backend struct {
+	// Name prefixes span names and ends the instrumentation scope.
+	Name string
+	// System is the db.system span attribute.
+	System string
+}
+
+// StartQuery starts a span for a repository operation. The returned finish
+// ends the span and records the operation latency, call it once with the
+// error the operation returns.
+func (b Backend) StartQuery(ctx context.Context, operation string) (context.Context, func(err error)) {
+	start := time.Now()
+
+	// Not cached in the Backend, tests install their own tracer provider
+	// after the backends are declared.
+	tracer := otel.Tracer("nasa-apod-app/internal/repository/" + b.Name)
+	ctx, span := tracer.Start(ctx, b.Name+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", b.System),
+			attribute.String("db.operation.name", operation),
+		),
+	)
+
+	return ctx, func(err error) {
+		metrics.ObserveQuery(operation, start)
+
+		// A date that is already stored is an expected outcome, not a failure.
+		if err != nil && !errors.Is(err, domain.ErrAlreadyExists) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
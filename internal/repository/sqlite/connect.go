@@ -0,0 +1,42 @@
+package sqlite
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+)
+
+// Open opens the database file at path, creating it and its directory when
+// missing. Foreign keys are enforced, WAL lets readers work alongside the
+// writer and write transactions take the lock up front instead of failing
+// with SQLITE_BUSY halfway through.
+func Open(path string, logger *zap.Logger) (*sqlx.DB, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	query := url.Values{}
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	query.Set("_txlock", "immediate")
+
+	logger.Info("Opening sqlite database", zap.String("path", path))
+	db, err := sqlx.Open("sqlite", "file:"+path+"?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/repository/sqlbackend"
+)
+
+// SaveFetchRun stores times in UTC, the columns are compared as text.
+func (r *ApodImagesRepository) SaveFetchRun(ctx context.Context, run domain.FetchRun) (err error) {
+	ctx, finish := backend.StartQuery(ctx, "save_fetch_run")
+	defer func() { finish(err) }()
+
+	query := `
+       INSERT INTO fetch_runs ("trigger", status, target_date, started_at, finished_at, http_status, bytes_downloaded, attempts, error)
+       VALUES (?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?)
+   `
+
+	_, err = r.db.ExecContext(ctx, query,
+		run.Trigger,
+		run.Status,
+		run.TargetDate,
+		run.StartedAt.UTC(),
+		run.FinishedAt.UTC(),
+		run.HTTPStatus,
+		run.BytesDownloaded,
+		run.Attempts,
+		run.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save fetch run: %w", err)
+	}
+	return nil
+}
+
+func (r *ApodImagesRepository) GetFetchRuns(ctx context.Context, filter domain.FetchRunsFilter) (runs []domain.FetchRun, err error) {
+	ctx, finish := backend.StartQuery(ctx, "get_fetch_runs")
+	defer func() { finish(err) }()
+
+	query := `
+       SELECT id, "trigger", status, COALESCE(target_date, '') AS target_date, started_at, finished_at, http_status, bytes_downloaded, attempts, error
+       FROM fetch_runs
+   `
+
+	query, args := sqlbackend.FetchRunsQuery(query, filter)
+	err = r.db.SelectContext(ctx, &runs, r.db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fetch runs: %w", err)
+	}
+
+	return runs, nil
+}
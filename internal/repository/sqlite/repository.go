@@ -0,0 +1,322 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/repository/sqlbackend"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var backend = sqlbackend.Backend{Name: "sqlite", System: "sqlite"}
+
+type ApodImagesRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLiteRepository(db *sqlx.DB) *ApodImagesRepository {
+	return &ApodImagesRepository{
+		db: db,
+	}
+}
+
+func (r *ApodImagesRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *ApodImagesRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// SchemaVersion returns the version of the newest migration applied to the database.
+func (r *ApodImagesRepository) SchemaVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := r.db.GetContext(ctx, &version, "SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Save inserts metadata together with its assets. When the date is already
+// stored, onConflict decides between returning a domain.AlreadyExistsError
+// (skip) and overwriting the stored metadata (refresh).
+func (r *ApodImagesRepository) Save(ctx context.Context, metadata domain.ApodImageMetaData, onConflict string) (err error) {
+	ctx, finish := backend.StartQuery(ctx, "save")
+	defer func() { finish(err) }()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	conflictClause := "ON CONFLICT (date) DO NOTHING"
+	if onConflict == domain.ConflictPolicyRefresh {
+		conflictClause = `ON CONFLICT (date) DO UPDATE SET
+           title = excluded.title,
+           explanation = excluded.explanation,
+           storage_key = COALESCE(NULLIF(excluded.storage_key, ''), apod_images.storage_key),
+           copyright = excluded.copyright,
+           media_type = excluded.media_type,
+           url = excluded.url,
+           hd_url = excluded.hd_url,
+           thumbnail_url = excluded.thumbnail_url,
+           service_version = excluded.service_version`
+	}
+
+	query := `
+       INSERT INTO apod_images (title, explanation, date, storage_key, copyright, media_type, url, hd_url, thumbnail_url, service_version)
+       VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+       ` + conflictClause + `
+       RETURNING id
+   `
+	var imageId int
+	err = tx.GetContext(ctx, &imageId, query,
+		metadata.Title,
+		metadata.Explanation,
+		metadata.Date,
+		metadata.StorageKey,
+		metadata.Copyright,
+		metadata.MediaType,
+		metadata.URL,
+		metadata.HDURL,
+		metadata.ThumbnailURL,
+		metadata.ServiceVersion,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &domain.AlreadyExistsError{Date: metadata.Date}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save APOD data: %w", err)
+	}
+
+	assetQuery := `
+       INSERT INTO apod_assets (apod_image_id, variant, source_url, storage_key, sha256, size_bytes, content_type, width, height)
+       VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+       ON CONFLICT (apod_image_id, variant) DO UPDATE SET
+           source_url = excluded.source_url,
+           storage_key = excluded.storage_key,
+           sha256 = excluded.sha256,
+           size_bytes = excluded.size_bytes,
+           content_type = excluded.content_type,
+           width = excluded.width,
+           height = excluded.height
+   `
+	for _, asset := range metadata.Assets {
+		_, err = tx.ExecContext(ctx, assetQuery, imageId, asset.Variant, asset.SourceURL, asset.StorageKey, asset.SHA256, asset.Size, asset.ContentType, asset.Width, asset.Height)
+		if err != nil {
+			return fmt.Errorf("failed to save APOD %s asset: %w", asset.Variant, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit APOD data: %w", err)
+	}
+	return nil
+}
+
+func (r *ApodImagesRepository) GetImageByDate(ctx context.Context, date string) (image *domain.ApodImageMetaData, err error) {
+	ctx, finish := backend.StartQuery(ctx, "get_image_by_date")
+	defer func() { finish(err) }()
+
+	query := `
+       SELECT id, title, explanation, date, storage_key, copyright, media_type, url, hd_url, thumbnail_url, service_version
+       FROM apod_images
+       WHERE date = ?
+   `
+
+	var imageMeta domain.ApodImageMetaData
+	err = r.db.GetContext(ctx, &imageMeta, query, date)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrNotFound, date)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get APOD image by date: %w", err)
+	}
+
+	images := []domain.ApodImageMetaData{imageMeta}
+	if err = r.attachAssets(ctx, images); err != nil {
+		return nil, err
+	}
+
+	return &images[0], nil
+}
+
+func (r *ApodImagesRepository) GetAllImages(ctx context.Context, filter domain.ApodImagesFilter) (images []domain.ApodImageMetaData, err error) {
+	ctx, finish := backend.StartQuery(ctx, "get_all_images")
+	defer func() { finish(err) }()
+
+	query := `
+       SELECT id, title, explanation, date, storage_key, copyright, media_type, url, hd_url, thumbnail_url, service_version
+       FROM apod_images
+   `
+
+	query, args := sqlbackend.ImagesQuery(query, filter)
+	err = r.db.SelectContext(ctx, &images, r.db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all APOD images: %w", err)
+	}
+
+	if err = r.attachAssets(ctx, images); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// Search ranks matches with bm25, title matches weigh more than matches in
+// the explanation like they do in the PostgreSQL backend.
+func (r *ApodImagesRepository) Search(ctx context.Context, search domain.ApodSearchQuery) (results []domain.ApodSearchResult, err error) {
+	ctx, finish := backend.StartQuery(ctx, "search")
+	defer func() { finish(err) }()
+
+	match := matchQuery(search.Query)
+	if match == "" {
+		return nil, nil
+	}
+
+	query := `
+       SELECT i.id, i.title, i.explanation, i.date, i.storage_key, i.copyright, i.media_type, i.url, i.hd_url, i.thumbnail_url, i.service_version,
+              -bm25(apod_images_search, 10.0, 1.0) AS rank,
+              highlight(apod_images_search, 0, '<mark>', '</mark>') AS title_highlight,
+              snippet(apod_images_search, 1, '<mark>', '</mark>', '...', 30) AS snippet
+       FROM apod_images_search
+       JOIN apod_images i ON i.id = apod_images_search.rowid
+       WHERE apod_images_search MATCH ?
+       ORDER BY bm25(apod_images_search, 10.0, 1.0), i.date DESC
+       LIMIT ? OFFSET ?
+   `
+
+	err = r.db.SelectContext(ctx, &results, query, match, search.Limit, search.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search APOD images: %w", err)
+	}
+
+	images := make([]domain.ApodImageMetaData, len(results))
+	for i := range results {
+		images[i] = results[i].ApodImageMetaData
+	}
+
+	if err = r.attachAssets(ctx, images); err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].Assets = images[i].Assets
+	}
+
+	return results, nil
+}
+
+// matchQuery translates the web search syntax PostgreSQL's
+// websearch_to_tsquery accepts ("quoted phrases", or, -excluded) into an FTS5
+// query. Terms are quoted, so FTS5 operators in the input are searched for
+// literally. Exclusions need a term before them, FTS5 has no unary NOT.
+func matchQuery(input string) string {
+	var query strings.Builder
+	var or, not bool
+
+	rest := strings.TrimSpace(input)
+	for rest != "" {
+		var term string
+		switch {
+		case rest[0] == '-':
+			not, rest = true, rest[1:]
+			continue
+		case rest[0] == '"':
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				term, rest = rest[1:], ""
+			} else {
+				term, rest = rest[1:end+1], rest[end+2:]
+			}
+		default:
+			end := strings.IndexAny(rest, " \t\r\n\"")
+			if end < 0 {
+				end = len(rest)
+			}
+			term, rest = rest[:end], rest[end:]
+			if strings.EqualFold(term, "or") {
+				or = true
+				rest = strings.TrimSpace(rest)
+				continue
+			}
+		}
+		rest = strings.TrimSpace(rest)
+
+		term = strings.TrimSpace(term)
+		if term == "" || (not && query.Len() == 0) {
+			or, not = false, false
+			continue
+		}
+
+		switch {
+		case query.Len() == 0:
+		case not:
+			query.WriteString(" NOT ")
+		case or:
+			query.WriteString(" OR ")
+		default:
+			query.WriteString(" AND ")
+		}
+		query.WriteString(`"` + strings.ReplaceAll(term, `"`, `""`) + `"`)
+		or, not = false, false
+	}
+	return query.String()
+}
+
+func (r *ApodImagesRepository) ExistsByDate(ctx context.Context, date string) (exists bool, err error) {
+	ctx, finish := backend.StartQuery(ctx, "exists_by_date")
+	defer func() { finish(err) }()
+
+	var count int
+	query := "SELECT COUNT(1) FROM apod_images WHERE date = ?"
+
+	err = r.db.GetContext(ctx, &count, query, date)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *ApodImagesRepository) attachAssets(ctx context.Context, images []domain.ApodImageMetaData) error {
+	if len(images) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(images))
+	byId := make(map[int]int, len(images))
+	for i := range images {
+		ids = append(ids, images[i].Id)
+		byId[images[i].Id] = i
+		images[i].Assets = []domain.ApodAsset{}
+	}
+
+	query, args, err := sqlx.In(`
+       SELECT id, apod_image_id, variant, source_url, storage_key, sha256, size_bytes, content_type, width, height
+       FROM apod_assets
+       WHERE apod_image_id IN (?)
+       ORDER BY id
+   `, ids)
+	if err != nil {
+		return fmt.Errorf("failed to build APOD assets query: %w", err)
+	}
+
+	var assets []domain.ApodAsset
+	err = r.db.SelectContext(ctx, &assets, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get APOD assets: %w", err)
+	}
+
+	for _, asset := range assets {
+		i := byId[asset.ImageId]
+		images[i].Assets = append(images[i].Assets, asset)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/metrics"
+	"nasa-apod-app/internal/migration"
+	"nasa-apod-app/internal/repository/repotest"
+	"nasa-apod-app/internal/service"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestRepository(t *testing.T) *ApodImagesRepository {
+	t.Helper()
+
+	db, err := Open(filepath.Join(t.TempDir(), "apod.db"), zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, migration.NewSQLiteMigration().Up(context.Background(), db))
+	return NewSQLiteRepository(db)
+}
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) service.ApodImagesRepo {
+		return newTestRepository(t)
+	})
+}
+
+func TestFetchRunsConformance(t *testing.T) {
+	repotest.RunFetchRuns(t, func(t *testing.T) service.FetchRunsRepo {
+		return newTestRepository(t)
+	})
+}
+
+func TestSchemaVersion(t *testing.T) {
+	repo := newTestRepository(t)
+
+	version, err := repo.SchemaVersion(context.Background())
+	require.NoError(t, err)
+
+	latest, err := migration.NewSQLiteMigration().LatestVersion()
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+}
+
+func TestQueryMetrics(t *testing.T) {
+	repo := newTestRepository(t)
+	saves := metrics.DBQueryDuration.WithLabelValues("save")
+
+	var before dto.Metric
+	require.NoError(t, saves.(prometheus.Metric).Write(&before))
+
+	require.NoError(t, repo.Save(context.Background(), domain.ApodImageMetaData{Date: "2024-01-01"}, domain.ConflictPolicySkip))
+
+	var after dto.Metric
+	require.NoError(t, saves.(prometheus.Metric).Write(&after))
+	assert.Equal(t, before.GetHistogram().GetSampleCount()+1, after.GetHistogram().GetSampleCount())
+}
+
+func TestMatchQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"galaxy", `"galaxy"`},
+		{"spiral galaxy", `"spiral" AND "galaxy"`},
+		{`"spiral galaxy" nebula`, `"spiral galaxy" AND "nebula"`},
+		{"moon or mars", `"moon" OR "mars"`},
+		{"galaxy -spiral", `"galaxy" NOT "spiral"`},
+		{"-spiral", ""},
+		{`NEAR(a b)`, `"NEAR(a" AND "b)"`},
+		{`"unterminated phrase`, `"unterminated phrase"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchQuery(tt.input))
+		})
+	}
+}
@@ -4,12 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"nasa-apod-app/internal/config"
 	"net/http"
-	"os"
-	"os/signal"
-	"time"
 )
 
 type Server struct {
@@ -25,26 +21,19 @@ func NewServer(config config.ServerConfig, handler http.Handler) *Server {
 	}
 }
 
-func (s *Server) Run() error {
+// Start serves HTTP in the background. The returned channel receives an error
+// if the server stops for any reason other than Shutdown.
+func (s *Server) Start() <-chan error {
+	errCh := make(chan error, 1)
 	go func() {
 		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("Failed to start server: %v\n", err)
+			errCh <- fmt.Errorf("failed to start server: %w", err)
 		}
+		close(errCh)
 	}()
+	return errCh
+}
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, os.Kill)
-
-	<-quit
-	log.Println("Shutting down server...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := s.server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
-	}
-
-	log.Println("Server stopped gracefully.")
-	return nil
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
 }
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"mime"
+	"net/http"
+
+	// Decoders used by image.DecodeConfig to read dimensions.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// sniffLen is how much of a download is inspected to detect its content type.
+const sniffLen = 512
+
+var imageExtensions = map[string]string{
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/bmp":     ".bmp",
+	"image/tiff":    ".tif",
+	"image/svg+xml": ".svg",
+}
+
+// detectContentType trusts the downloaded bytes over the server's
+// Content-Type header, which is only used when the bytes are inconclusive.
+func detectContentType(head []byte, header string) string {
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if detected != "application/octet-stream" && detected != "text/plain" {
+		return detected
+	}
+
+	if declared, _, err := mime.ParseMediaType(header); err == nil && declared != "" {
+		return declared
+	}
+	return detected
+}
+
+func extensionForContentType(contentType string) string {
+	if extension, ok := imageExtensions[contentType]; ok {
+		return extension
+	}
+
+	if extensions, err := mime.ExtensionsByType(contentType); err == nil && len(extensions) > 0 {
+		return extensions[0]
+	}
+	return ".bin"
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeTestImage(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	default:
+		t.Fatalf("unsupported test image format %q", format)
+	}
+	assert.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestDetectContentType(t *testing.T) {
+	pngImage := encodeTestImage(t, "png", 1, 1)
+
+	tests := []struct {
+		name     string
+		head     []byte
+		header   string
+		expected string
+	}{
+		{name: "bytes win over a wrong header", head: pngImage, header: "image/jpeg", expected: "image/png"},
+		{name: "header is used for inconclusive bytes", head: []byte{0x00, 0x01}, header: "image/tiff", expected: "image/tiff"},
+		{name: "header parameters are dropped", head: []byte("plain"), header: "image/svg+xml; charset=utf-8", expected: "image/svg+xml"},
+		{name: "no header keeps the sniffed type", head: []byte{0x00, 0x01}, expected: "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, detectContentType(tt.head, tt.header))
+		})
+	}
+}
+
+func TestStorageKey(t *testing.T) {
+	assert.Equal(t, "apod/2024-01-01.jpg", storageKey("2024-01-01", "standard", "image/jpeg"))
+	assert.Equal(t, "apod/2024-01-01_hd.png", storageKey("2024-01-01", "hd", "image/png"))
+	assert.Equal(t, "apod/2024-01-01_thumbnail.webp", storageKey("2024-01-01", "thumbnail", "image/webp"))
+	assert.Equal(t, "apod/2024-01-01.bin", storageKey("2024-01-01", "standard", "application/x-unknown"))
+}
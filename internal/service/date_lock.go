@@ -0,0 +1,41 @@
+package service
+
+import "sync"
+
+// dateLocks serializes work on the same APOD date, so a manual fetch and a
+// scheduled one cannot both pass ExistsByDate and save the day twice.
+type dateLocks struct {
+	mu    sync.Mutex
+	locks map[string]*dateLock
+}
+
+type dateLock struct {
+	mu      sync.Mutex
+	waiters int
+}
+
+func (l *dateLocks) lock(date string) (unlock func()) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*dateLock)
+	}
+	lock, ok := l.locks[date]
+	if !ok {
+		lock = &dateLock{}
+		l.locks[date] = lock
+	}
+	lock.waiters++
+	l.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		l.mu.Lock()
+		lock.waiters--
+		if lock.waiters == 0 {
+			delete(l.locks, date)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"nasa-apod-app/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+// Export writes the stored entries between from and to (inclusive, both
+// optional) to w as JSON lines, oldest first, and returns how many it wrote.
+func (s *ApodImagesService) Export(ctx context.Context, w io.Writer, from, to string) (int, error) {
+	encoder := json.NewEncoder(w)
+
+	var exported int
+	filter := domain.ApodImagesFilter{From: from, To: to, Order: domain.SortOrderAsc, Limit: MaxPageLimit}
+	for {
+		page, err := s.GetAllImages(ctx, filter)
+		if errors.Is(err, ErrImagesNotFound) {
+			break
+		}
+		if err != nil {
+			return exported, fmt.Errorf("failed to list APOD images: %w", err)
+		}
+
+		for _, image := range page.Images {
+			if err := encoder.Encode(image); err != nil {
+				return exported, fmt.Errorf("failed to write %s: %w", image.Date, err)
+			}
+			exported++
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+
+	s.logger.Info("Exported APOD entries", zap.Int("entries", exported))
+	return exported, nil
+}
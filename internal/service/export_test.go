@@ -0,0 +1,60 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/models"
+	"nasa-apod-app/internal/repository/memory"
+	"nasa-apod-app/internal/storage/local"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewMemoryRepository()
+	apodService := NewApodImagesService(zap.NewNop(), repo, local.NewLocalStorage(t.TempDir()), config.WorkerConfig{})
+
+	t.Run("empty archive", func(t *testing.T) {
+		var out bytes.Buffer
+		exported, err := apodService.Export(ctx, &out, "", "")
+		assert.NoError(t, err)
+		assert.Equal(t, 0, exported)
+		assert.Empty(t, out.String())
+	})
+
+	for _, date := range []string{"2024-01-03", "2024-01-01", "2024-01-02", "2024-01-04"} {
+		err := apodService.SaveAPODData(ctx, models.APODResponse{Date: date, Title: "APOD " + date, MediaType: domain.MediaTypeVideo, URL: "https://example.com/" + date})
+		assert.NoError(t, err)
+	}
+
+	exportDates := func(t *testing.T, from, to string) []string {
+		var out bytes.Buffer
+		exported, err := apodService.Export(ctx, &out, from, to)
+		assert.NoError(t, err)
+
+		var dates []string
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			var image domain.ApodImageMetaData
+			assert.NoError(t, json.Unmarshal([]byte(line), &image))
+			assert.Equal(t, "APOD "+image.Date, image.Title)
+			dates = append(dates, image.Date)
+		}
+		assert.Equal(t, len(dates), exported)
+		return dates
+	}
+
+	t.Run("everything oldest first", func(t *testing.T) {
+		assert.Equal(t, []string{"2024-01-01", "2024-01-02", "2024-01-03", "2024-01-04"}, exportDates(t, "", ""))
+	})
+
+	t.Run("date range", func(t *testing.T) {
+		assert.Equal(t, []string{"2024-01-02", "2024-01-03"}, exportDates(t, "2024-01-02", "2024-01-03"))
+	})
+}
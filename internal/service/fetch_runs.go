@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/metrics"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type FetchRunsRepo interface {
+	SaveFetchRun(ctx context.Context, run domain.FetchRun) error
+	GetFetchRuns(ctx context.Context, filter domain.FetchRunsFilter) ([]domain.FetchRun, error)
+}
+
+var (
+	ErrInvalidFetchStatus  = fmt.Errorf("invalid fetch run status provided. use succeeded, skipped or failed")
+	ErrInvalidFetchTrigger = fmt.Errorf("invalid fetch run trigger provided. use schedule, manual or backfill")
+	ErrFetchRunsDisabled   = fmt.Errorf("fetch run history is not available")
+)
+
+// fetchStats collects what the HTTP calls of a single fetch did. It travels in
+// the context so the retry policy and downloads can report into it.
+type fetchStats struct {
+	mu         sync.Mutex
+	attempts   int
+	httpStatus int
+	bytes      int64
+}
+
+type fetchStatsKey struct{}
+
+func withFetchStats(ctx context.Context) (context.Context, *fetchStats) {
+	stats := &fetchStats{}
+	return context.WithValue(ctx, fetchStatsKey{}, stats), stats
+}
+
+func fetchStatsFrom(ctx context.Context) *fetchStats {
+	stats, _ := ctx.Value(fetchStatsKey{}).(*fetchStats)
+	return stats
+}
+
+func (s *fetchStats) addAttempt() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+}
+
+func (s *fetchStats) setHTTPStatus(status int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.httpStatus = status
+}
+
+func (s *fetchStats) addBytes(n int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bytes += n
+}
+
+func (w *APODWorker) recordFetchRun(ctx context.Context, trigger, date string, startedAt time.Time, stats *fetchStats, fetchErr error) {
+	status := domain.FetchStatusSucceeded
+	switch {
+	case errors.Is(fetchErr, domain.ErrAlreadyExists):
+		status = domain.FetchStatusSkipped
+	case fetchErr != nil:
+		status = domain.FetchStatusFailed
+	}
+
+	finishedAt := w.clock().Now()
+	metrics.Fetches.WithLabelValues(trigger, status).Inc()
+	if status == domain.FetchStatusSucceeded {
+		metrics.LastSuccessfulFetch.Set(float64(finishedAt.Unix()))
+	}
+	if status != domain.FetchStatusFailed {
+		w.lastFetch.Store(finishedAt.UnixNano())
+	}
+
+	if w.Runs == nil {
+		return
+	}
+
+	stats.mu.Lock()
+	run := domain.FetchRun{
+		Trigger:         trigger,
+		Status:          status,
+		TargetDate:      date,
+		StartedAt:       startedAt,
+		FinishedAt:      finishedAt,
+		HTTPStatus:      stats.httpStatus,
+		BytesDownloaded: stats.bytes,
+		Attempts:        stats.attempts,
+	}
+	stats.mu.Unlock()
+
+	if status == domain.FetchStatusFailed {
+		run.Error = fetchErr.Error()
+	}
+
+	// Aborted fetches are worth recording too, so the run outlives the fetch context.
+	if err := w.Runs.SaveFetchRun(context.WithoutCancel(ctx), run); err != nil {
+		w.Logger.Warn("Failed to record fetch run", zap.String("date", date), zap.Error(err))
+	}
+}
+
+func (w *APODWorker) GetFetchRuns(ctx context.Context, filter domain.FetchRunsFilter) ([]domain.FetchRun, error) {
+	if w.Runs == nil {
+		return nil, ErrFetchRunsDisabled
+	}
+
+	for _, date := range []string{filter.From, filter.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(apodDateLayout, date); err != nil {
+			return nil, ErrInvalidDate
+		}
+	}
+
+	switch filter.Status {
+	case "", domain.FetchStatusSucceeded, domain.FetchStatusSkipped, domain.FetchStatusFailed:
+	default:
+		return nil, ErrInvalidFetchStatus
+	}
+
+	switch filter.Trigger {
+	case "", domain.FetchTriggerSchedule, domain.FetchTriggerManual, domain.FetchTriggerBackfill:
+	default:
+		return nil, ErrInvalidFetchTrigger
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = DefaultPageLimit
+	}
+	if filter.Limit < 0 || filter.Limit > MaxPageLimit {
+		return nil, ErrInvalidLimit
+	}
+
+	return w.Runs.GetFetchRuns(ctx, filter)
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/models"
+	"nasa-apod-app/internal/repository/memory"
+	"nasa-apod-app/internal/storage/local"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type InMemoryFetchRunsRepo struct {
+	runs []domain.FetchRun
+}
+
+func (r *InMemoryFetchRunsRepo) SaveFetchRun(ctx context.Context, run domain.FetchRun) error {
+	run.Id = len(r.runs) + 1
+	r.runs = append(r.runs, run)
+	return nil
+}
+
+func (r *InMemoryFetchRunsRepo) GetFetchRuns(ctx context.Context, filter domain.FetchRunsFilter) ([]domain.FetchRun, error) {
+	var runs []domain.FetchRun
+	for i := len(r.runs) - 1; i >= 0; i-- {
+		run := r.runs[i]
+		if filter.Status != "" && run.Status != filter.Status {
+			continue
+		}
+		if filter.Trigger != "" && run.Trigger != filter.Trigger {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func TestFetchRuns(t *testing.T) {
+	var apiRequests int
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/apod", func(w http.ResponseWriter, r *http.Request) {
+		apiRequests++
+		if apiRequests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Query().Get("date") == "2024-01-02" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(models.APODResponse{Date: "2024-01-01", MediaType: "image", URL: srv.URL + "/image"})
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image"))
+	})
+
+	logger := zap.NewNop()
+	runs := &InMemoryFetchRunsRepo{}
+	retryConfig := config.RetryConfig{MaxAttempts: 2, RetryableStatusCodes: []int{http.StatusServiceUnavailable}}
+	worker := &APODWorker{
+		ApodService: NewApodImagesService(logger, memory.NewMemoryRepository(), local.NewLocalStorage(t.TempDir()), config.WorkerConfig{Retry: retryConfig}),
+		Runs:        runs,
+		ApodURL:     srv.URL + "/apod",
+		Logger:      logger,
+	}
+
+	t.Run("records a successful fetch of the latest APOD", func(t *testing.T) {
+		err := worker.fetchAPOD(context.Background(), "", domain.FetchTriggerSchedule)
+		assert.NoError(t, err)
+
+		run := runs.runs[0]
+		assert.Equal(t, domain.FetchTriggerSchedule, run.Trigger)
+		assert.Equal(t, domain.FetchStatusSucceeded, run.Status)
+		assert.Equal(t, "2024-01-01", run.TargetDate)
+		assert.Equal(t, http.StatusOK, run.HTTPStatus)
+		assert.Equal(t, 3, run.Attempts)
+		assert.Equal(t, int64(len("image")), run.BytesDownloaded)
+		assert.Empty(t, run.Error)
+	})
+
+	t.Run("records skipped and failed fetches", func(t *testing.T) {
+		err := worker.fetchAPOD(context.Background(), "2024-01-01", domain.FetchTriggerManual)
+		assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+
+		err = worker.fetchAPOD(context.Background(), "2024-01-02", domain.FetchTriggerManual)
+		assert.Error(t, err)
+
+		assert.Equal(t, domain.FetchStatusSkipped, runs.runs[1].Status)
+
+		failed := runs.runs[2]
+		assert.Equal(t, domain.FetchStatusFailed, failed.Status)
+		assert.Equal(t, "2024-01-02", failed.TargetDate)
+		assert.Equal(t, http.StatusNotFound, failed.HTTPStatus)
+		assert.Equal(t, 1, failed.Attempts)
+		assert.Contains(t, failed.Error, "404")
+	})
+
+	t.Run("filters runs", func(t *testing.T) {
+		found, err := worker.GetFetchRuns(context.Background(), domain.FetchRunsFilter{Status: domain.FetchStatusFailed})
+		assert.NoError(t, err)
+		assert.Len(t, found, 1)
+		assert.Equal(t, "2024-01-02", found[0].TargetDate)
+
+		_, err = worker.GetFetchRuns(context.Background(), domain.FetchRunsFilter{Status: "lost"})
+		assert.Equal(t, ErrInvalidFetchStatus, err)
+
+		_, err = worker.GetFetchRuns(context.Background(), domain.FetchRunsFilter{Trigger: "cron"})
+		assert.Equal(t, ErrInvalidFetchTrigger, err)
+
+		_, err = worker.GetFetchRuns(context.Background(), domain.FetchRunsFilter{From: "01/01/2024"})
+		assert.Equal(t, ErrInvalidDate, err)
+	})
+
+	t.Run("records backfilled days", func(t *testing.T) {
+		runs.runs = nil
+		mux.HandleFunc("/range", func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode([]models.APODResponse{{Date: "2024-01-03", MediaType: "video"}})
+		})
+		worker.ApodURL = srv.URL + "/range"
+
+		err := worker.Backfill(context.Background(), time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC))
+		assert.NoError(t, err)
+
+		assert.Len(t, runs.runs, 1)
+		assert.Equal(t, domain.FetchTriggerBackfill, runs.runs[0].Trigger)
+		assert.Equal(t, "2024-01-03", runs.runs[0].TargetDate)
+		assert.Equal(t, domain.FetchStatusSucceeded, runs.runs[0].Status)
+	})
+}
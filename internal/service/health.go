@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/storage"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	healthCheckTimeout = 3 * time.Second
+	storageProbeKey    = storage.ReservedPrefix + "readyz-probe"
+	// storageWriteInterval spaces out the write probes, readiness is polled
+	// far more often than storage is likely to turn read-only.
+	storageWriteInterval = 5 * time.Minute
+)
+
+type DatabaseHealth interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int64, error)
+}
+
+type FetchHealth interface {
+	LastSuccessfulFetch() time.Time
+}
+
+// HealthService reports whether the service can do its job: the database is
+// reachable and migrated, storage takes writes and the worker keeps fetching.
+type HealthService struct {
+	db            DatabaseHealth
+	storage       storage.Storage
+	worker        FetchHealth
+	schemaVersion int64
+	maxFetchAge   time.Duration
+	now           func() time.Time
+	logger        *zap.Logger
+
+	storageMu        sync.Mutex
+	storageWrittenAt time.Time
+}
+
+// NewHealthService expects the database at schemaVersion. A maxFetchAge of
+// zero disables the fetch staleness check.
+func NewHealthService(db DatabaseHealth, imageStorage storage.Storage, worker FetchHealth, schemaVersion int64, maxFetchAge time.Duration, logger *zap.Logger) *HealthService {
+	return &HealthService{
+		db:            db,
+		storage:       imageStorage,
+		worker:        worker,
+		schemaVersion: schemaVersion,
+		maxFetchAge:   maxFetchAge,
+		now:           time.Now,
+		logger:        logger,
+	}
+}
+
+// Ready runs every check concurrently, each with its own timeout.
+func (s *HealthService) Ready(ctx context.Context) domain.HealthReport {
+	checks := map[string]func(ctx context.Context) (string, error){
+		"database":   s.checkDatabase,
+		"migrations": s.checkMigrations,
+		"storage":    s.checkStorage,
+		"fetch":      s.checkFetch,
+	}
+
+	report := domain.HealthReport{Status: domain.HealthStatusOK, Checks: make(map[string]domain.HealthCheck, len(checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			result := domain.HealthCheck{Status: domain.HealthStatusOK}
+			detail, err := check(checkCtx)
+			result.Detail = detail
+			if err != nil {
+				result.Status = domain.HealthStatusFail
+				result.Error = err.Error()
+				s.logger.Warn("Readiness check failed", zap.String("check", name), zap.Error(err))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if err != nil {
+				report.Status = domain.HealthStatusFail
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (s *HealthService) checkDatabase(ctx context.Context) (string, error) {
+	return "", s.db.Ping(ctx)
+}
+
+func (s *HealthService) checkMigrations(ctx context.Context) (string, error) {
+	version, err := s.db.SchemaVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	detail := fmt.Sprintf("schema version %d", version)
+	if version < s.schemaVersion {
+		return detail, fmt.Errorf("database is at version %d, expected %d", version, s.schemaVersion)
+	}
+	return detail, nil
+}
+
+// checkStorage looks up the probe object on every call and writes and removes
+// it when the last successful write is older than storageWriteInterval.
+func (s *HealthService) checkStorage(ctx context.Context) (string, error) {
+	if _, err := s.storage.Stat(ctx, storageProbeKey); err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+		return "", fmt.Errorf("storage is not reachable: %w", err)
+	}
+
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	now := s.now()
+	if !s.storageWrittenAt.IsZero() && now.Sub(s.storageWrittenAt) < storageWriteInterval {
+		return "", nil
+	}
+
+	if _, err := s.storage.Put(ctx, storageProbeKey, strings.NewReader("ok"), "text/plain"); err != nil {
+		return "", fmt.Errorf("storage is not writable: %w", err)
+	}
+	if err := s.storage.Delete(ctx, storageProbeKey); err != nil {
+		return "", fmt.Errorf("failed to remove storage probe: %w", err)
+	}
+	s.storageWrittenAt = now
+	return "", nil
+}
+
+func (s *HealthService) checkFetch(ctx context.Context) (string, error) {
+	last := s.worker.LastSuccessfulFetch()
+	if last.IsZero() {
+		return "worker not started", nil
+	}
+
+	age := s.now().Sub(last).Round(time.Second)
+	detail := fmt.Sprintf("last successful fetch %s ago", age)
+	if s.maxFetchAge > 0 && age > s.maxFetchAge {
+		return detail, fmt.Errorf("no successful fetch for %s, allowed is %s", age, s.maxFetchAge)
+	}
+	return detail, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/storage"
+	"nasa-apod-app/internal/storage/local"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeDatabaseHealth struct {
+	pingErr error
+	version int64
+}
+
+func (f *fakeDatabaseHealth) Ping(ctx context.Context) error {
+	return f.pingErr
+}
+
+func (f *fakeDatabaseHealth) SchemaVersion(ctx context.Context) (int64, error) {
+	return f.version, f.pingErr
+}
+
+type fakeFetchHealth time.Time
+
+func (f fakeFetchHealth) LastSuccessfulFetch() time.Time {
+	return time.Time(f)
+}
+
+func TestHealthServiceReady(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	newHealthService := func(db *fakeDatabaseHealth, root string, lastFetch time.Time) *HealthService {
+		health := NewHealthService(db, local.NewLocalStorage(root), fakeFetchHealth(lastFetch), 9, 48*time.Hour, zap.NewNop())
+		health.now = func() time.Time { return now }
+		return health
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		root := t.TempDir()
+		report := newHealthService(&fakeDatabaseHealth{version: 9}, root, now.Add(-time.Hour)).Ready(context.Background())
+
+		assert.Equal(t, domain.HealthStatusOK, report.Status)
+		assert.Len(t, report.Checks, 4)
+		assert.Equal(t, "last successful fetch 1h0m0s ago", report.Checks["fetch"].Detail)
+
+		_, err := os.Stat(filepath.Join(root, storageProbeKey))
+		assert.True(t, os.IsNotExist(err), "probe object must be removed")
+	})
+
+	t.Run("database down", func(t *testing.T) {
+		report := newHealthService(&fakeDatabaseHealth{pingErr: fmt.Errorf("connection refused")}, t.TempDir(), now).Ready(context.Background())
+
+		assert.Equal(t, domain.HealthStatusFail, report.Status)
+		assert.Equal(t, domain.HealthCheck{Status: domain.HealthStatusFail, Error: "connection refused"}, report.Checks["database"])
+		assert.Equal(t, domain.HealthStatusFail, report.Checks["migrations"].Status)
+		assert.Equal(t, domain.HealthStatusOK, report.Checks["storage"].Status)
+	})
+
+	t.Run("pending migrations", func(t *testing.T) {
+		report := newHealthService(&fakeDatabaseHealth{version: 8}, t.TempDir(), now).Ready(context.Background())
+
+		assert.Equal(t, domain.HealthStatusFail, report.Status)
+		assert.Equal(t, "database is at version 8, expected 9", report.Checks["migrations"].Error)
+	})
+
+	t.Run("storage not writable", func(t *testing.T) {
+		root := filepath.Join(t.TempDir(), "file")
+		assert.NoError(t, os.WriteFile(root, nil, 0o644))
+
+		report := newHealthService(&fakeDatabaseHealth{version: 9}, root, now).Ready(context.Background())
+
+		assert.Equal(t, domain.HealthStatusFail, report.Status)
+		assert.Equal(t, domain.HealthStatusFail, report.Checks["storage"].Status)
+	})
+
+	t.Run("stale fetch", func(t *testing.T) {
+		report := newHealthService(&fakeDatabaseHealth{version: 9}, t.TempDir(), now.Add(-72*time.Hour)).Ready(context.Background())
+
+		assert.Equal(t, domain.HealthStatusFail, report.Status)
+		assert.Equal(t, "no successful fetch for 72h0m0s, allowed is 48h0m0s", report.Checks["fetch"].Error)
+	})
+}
+
+type countingStorage struct {
+	storage.Storage
+	puts int
+}
+
+func (s *countingStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) (storage.ObjectInfo, error) {
+	s.puts++
+	return s.Storage.Put(ctx, key, r, contentType)
+}
+
+func TestHealthServiceStorageWrites(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	imageStorage := &countingStorage{Storage: local.NewLocalStorage(t.TempDir())}
+	health := NewHealthService(&fakeDatabaseHealth{version: 9}, imageStorage, fakeFetchHealth(now), 9, 0, zap.NewNop())
+	health.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		report := health.Ready(context.Background())
+		assert.Equal(t, domain.HealthStatusOK, report.Checks["storage"].Status)
+	}
+	assert.Equal(t, 1, imageStorage.puts, "readiness checks in quick succession write once")
+
+	now = now.Add(storageWriteInterval)
+	health.Ready(context.Background())
+	assert.Equal(t, 2, imageStorage.puts)
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"nasa-apod-app/internal/domain"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrJobNotFound          = fmt.Errorf("job not found")
+	ErrJobQueueFull         = fmt.Errorf("job queue is full, try again later")
+	ErrMissingBackfillStart = fmt.Errorf("backfill start date is required")
+)
+
+const (
+	jobQueueSize    = 100
+	maxFinishedJobs = 500
+)
+
+type jobFunc func(ctx context.Context, report func(domain.JobProgress)) (string, error)
+
+type pendingJob struct {
+	id  string
+	run jobFunc
+}
+
+// jobQueue keeps track of manually triggered jobs. Jobs with the same key are
+// coalesced while one of them is still queued or running.
+type jobQueue struct {
+	once     sync.Once
+	mu       sync.Mutex
+	jobs     map[string]*domain.Job
+	keys     map[string]string
+	active   map[string]string
+	finished []string
+	pending  chan pendingJob
+}
+
+func (q *jobQueue) init() {
+	q.once.Do(func() {
+		q.jobs = make(map[string]*domain.Job)
+		q.keys = make(map[string]string)
+		q.active = make(map[string]string)
+		q.pending = make(chan pendingJob, jobQueueSize)
+	})
+}
+
+func (q *jobQueue) enqueue(job domain.Job, key string, run jobFunc) (domain.Job, error) {
+	q.init()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if id, ok := q.active[key]; ok {
+		return *q.jobs[id], nil
+	}
+
+	job.Id = uuid.NewString()
+	job.Status = domain.JobStatusQueued
+	job.CreatedAt = time.Now()
+
+	select {
+	case q.pending <- pendingJob{id: job.Id, run: run}:
+	default:
+		return domain.Job{}, ErrJobQueueFull
+	}
+
+	q.jobs[job.Id] = &job
+	q.keys[job.Id] = key
+	q.active[key] = job.Id
+	return job, nil
+}
+
+func (q *jobQueue) get(id string) (domain.Job, error) {
+	q.init()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return domain.Job{}, ErrJobNotFound
+	}
+	return *job, nil
+}
+
+func (q *jobQueue) run(ctx context.Context, pending pendingJob) (domain.Job, error) {
+	q.update(pending.id, func(job *domain.Job) {
+		now := time.Now()
+		job.Status = domain.JobStatusRunning
+		job.StartedAt = &now
+	})
+
+	result, err := pending.run(ctx, func(progress domain.JobProgress) {
+		q.update(pending.id, func(job *domain.Job) { job.Progress = progress })
+	})
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job := q.jobs[pending.id]
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Result = result
+	job.Status = domain.JobStatusSucceeded
+	if err != nil {
+		job.Status = domain.JobStatusFailed
+		job.Error = err.Error()
+	}
+
+	delete(q.active, q.keys[pending.id])
+	delete(q.keys, pending.id)
+
+	q.finished = append(q.finished, pending.id)
+	for len(q.finished) > maxFinishedJobs {
+		delete(q.jobs, q.finished[0])
+		q.finished = q.finished[1:]
+	}
+
+	return *job, err
+}
+
+func (q *jobQueue) update(id string, fn func(job *domain.Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	fn(q.jobs[id])
+}
+
+// EnqueueFetch queues a fetch of the APOD for date, or of the latest one when
+// date is empty. A fetch for the same date that is still pending is returned
+// instead of queueing a second one.
+func (w *APODWorker) EnqueueFetch(date string) (domain.Job, error) {
+	if date != "" {
+		if _, err := time.Parse(apodDateLayout, date); err != nil {
+			return domain.Job{}, ErrInvalidDate
+		}
+	}
+
+	job := domain.Job{Type: domain.JobTypeFetch, Date: date}
+	return w.jobs.enqueue(job, domain.JobTypeFetch+":"+date, func(ctx context.Context, report func(domain.JobProgress)) (string, error) {
+		progress := domain.JobProgress{Total: 1, Done: 1}
+
+		err := w.fetchAPOD(ctx, date, domain.FetchTriggerManual)
+		switch {
+		case err == nil:
+			progress.Saved++
+			report(progress)
+			return "saved", nil
+		case errors.Is(err, domain.ErrAlreadyExists):
+			progress.Skipped++
+			report(progress)
+			return "already saved", nil
+		default:
+			progress.Failed++
+			report(progress)
+			return "", err
+		}
+	})
+}
+
+// EnqueueBackfill queues a backfill between from and to (inclusive), to
+// defaults to today.
+func (w *APODWorker) EnqueueBackfill(from, to string) (domain.Job, error) {
+	if from == "" {
+		return domain.Job{}, ErrMissingBackfillStart
+	}
+
+	startDate, err := time.Parse(apodDateLayout, from)
+	if err != nil {
+		return domain.Job{}, ErrInvalidDate
+	}
+
+	var endDate time.Time
+	if to != "" {
+		endDate, err = time.Parse(apodDateLayout, to)
+		if err != nil {
+			return domain.Job{}, ErrInvalidDate
+		}
+
+		if endDate.Before(startDate) {
+			return domain.Job{}, ErrInvalidBackfillRange
+		}
+	}
+
+	job := domain.Job{Type: domain.JobTypeBackfill, From: from, To: to}
+	return w.jobs.enqueue(job, domain.JobTypeBackfill+":"+from+":"+to, func(ctx context.Context, report func(domain.JobProgress)) (string, error) {
+		var saved int
+		err := w.backfill(ctx, startDate, endDate, func(progress domain.JobProgress) {
+			saved = progress.Saved
+			report(progress)
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("saved %d entries", saved), nil
+	})
+}
+
+func (w *APODWorker) GetJob(id string) (domain.Job, error) {
+	return w.jobs.get(id)
+}
+
+func (w *APODWorker) runJobs(ctx context.Context) {
+	w.jobs.init()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case pending := <-w.jobs.pending:
+			w.goTracked(func() {
+				job, err := w.jobs.run(ctx, pending)
+				if err != nil {
+					w.Logger.Error("APOD job failed", zap.String("job_id", job.Id), zap.String("type", job.Type), zap.Error(err))
+					return
+				}
+				w.Logger.Info("APOD job finished", zap.String("job_id", job.Id), zap.String("type", job.Type), zap.String("result", job.Result))
+			})
+		}
+	}
+}
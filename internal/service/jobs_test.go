@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/models"
+	"nasa-apod-app/internal/repository/memory"
+	"nasa-apod-app/internal/storage/local"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestAPODWorkerJobs(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		query := r.URL.Query()
+		if query.Get("start_date") != "" {
+			json.NewEncoder(w).Encode([]models.APODResponse{
+				{Date: "2024-01-02", MediaType: "video"},
+				{Date: "2024-01-03", MediaType: "video"},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(models.APODResponse{Date: query.Get("date"), MediaType: "video"})
+	}))
+	defer srv.Close()
+
+	logger := zap.NewNop()
+	repo := memory.NewMemoryRepository()
+	worker := &APODWorker{
+		ApodService:       NewApodImagesService(logger, repo, local.NewLocalStorage(t.TempDir()), config.WorkerConfig{}),
+		ApodURL:           srv.URL,
+		RunTime:           time.Now().Add(time.Hour),
+		BackfillBatchDays: 30,
+		Logger:            logger,
+	}
+
+	waitForJob := func(t *testing.T, id string) domain.Job {
+		t.Helper()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			job, err := worker.GetJob(id)
+			assert.NoError(t, err)
+			if job.Status == domain.JobStatusSucceeded || job.Status == domain.JobStatusFailed {
+				return job
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("job %s did not finish", id)
+		return domain.Job{}
+	}
+
+	t.Run("coalesces fetches for the same date", func(t *testing.T) {
+		first, err := worker.EnqueueFetch("2024-01-01")
+		assert.NoError(t, err)
+		assert.Equal(t, domain.JobStatusQueued, first.Status)
+
+		second, err := worker.EnqueueFetch("2024-01-01")
+		assert.NoError(t, err)
+		assert.Equal(t, first.Id, second.Id)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		worker.Start(ctx)
+
+		job := waitForJob(t, first.Id)
+		assert.Equal(t, domain.JobStatusSucceeded, job.Status)
+		assert.Equal(t, "saved", job.Result)
+		assert.Equal(t, domain.JobProgress{Total: 1, Done: 1, Saved: 1}, job.Progress)
+		assert.Equal(t, 1, requests)
+
+		again, err := worker.EnqueueFetch("2024-01-01")
+		assert.NoError(t, err)
+		assert.NotEqual(t, first.Id, again.Id)
+
+		job = waitForJob(t, again.Id)
+		assert.Equal(t, "already saved", job.Result)
+
+		cancel()
+		assert.NoError(t, worker.Wait(context.Background()))
+	})
+
+	t.Run("reports backfill progress", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		worker.Start(ctx)
+
+		job, err := worker.EnqueueBackfill("2024-01-01", "2024-01-03")
+		assert.NoError(t, err)
+
+		job = waitForJob(t, job.Id)
+		assert.Equal(t, domain.JobStatusSucceeded, job.Status)
+		assert.Equal(t, domain.JobProgress{Total: 3, Done: 3, Saved: 2, Skipped: 1}, job.Progress)
+		assert.Equal(t, "2024-01-03", storedImage(t, repo, "2024-01-03").Date)
+	})
+
+	t.Run("rejects invalid input", func(t *testing.T) {
+		_, err := worker.EnqueueFetch("yesterday")
+		assert.Equal(t, ErrInvalidDate, err)
+
+		_, err = worker.EnqueueBackfill("", "")
+		assert.Equal(t, ErrMissingBackfillStart, err)
+
+		_, err = worker.EnqueueBackfill("2024-01-03", "2024-01-01")
+		assert.Equal(t, ErrInvalidBackfillRange, err)
+
+		_, err = worker.GetJob("missing")
+		assert.Equal(t, ErrJobNotFound, err)
+	})
+}
+
+func TestSaveAPODDataConcurrent(t *testing.T) {
+	logger := zap.NewNop()
+	repo := memory.NewMemoryRepository()
+	apodService := NewApodImagesService(logger, repo, local.NewLocalStorage(t.TempDir()), config.WorkerConfig{})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = apodService.SaveAPODData(context.Background(), models.APODResponse{Date: "2024-01-01", MediaType: "video"})
+		}()
+	}
+	wg.Wait()
+
+	var saved int
+	for _, err := range errs {
+		if err == nil {
+			saved++
+			continue
+		}
+		assert.True(t, errors.Is(err, domain.ErrAlreadyExists))
+	}
+	assert.Equal(t, 1, saved)
+}
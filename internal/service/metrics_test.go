@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/metrics"
+	"nasa-apod-app/internal/models"
+	"nasa-apod-app/internal/repository/memory"
+	"nasa-apod-app/internal/storage/local"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFetchMetrics(t *testing.T) {
+	image := encodeTestImage(t, "png", 2, 2)
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/apod", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.APODResponse{Date: "2024-01-01", MediaType: "image", URL: srv.URL + "/image.png"})
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(image)
+	})
+
+	logger := zap.NewNop()
+	finishedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	worker := &APODWorker{
+		ApodService: NewApodImagesService(logger, memory.NewMemoryRepository(), local.NewLocalStorage(t.TempDir()), config.WorkerConfig{}),
+		ApodURL:     srv.URL + "/apod",
+		Clock:       &fakeClock{now: finishedAt},
+		Logger:      logger,
+	}
+
+	// The metrics are global, only what the fetches add is compared so the
+	// test can run repeatedly.
+	succeeded := metrics.Fetches.WithLabelValues(domain.FetchTriggerManual, domain.FetchStatusSucceeded)
+	skipped := metrics.Fetches.WithLabelValues(domain.FetchTriggerManual, domain.FetchStatusSkipped)
+	downloaded := metrics.DownloadedBytes.WithLabelValues(domain.AssetVariantStandard)
+	downloads := metrics.DownloadDuration.WithLabelValues(domain.AssetVariantStandard)
+
+	succeededBefore, skippedBefore := testutil.ToFloat64(succeeded), testutil.ToFloat64(skipped)
+	attemptsBefore := testutil.ToFloat64(metrics.FetchAttempts)
+	downloadedBefore, downloadsBefore := testutil.ToFloat64(downloaded), sampleCount(t, downloads)
+
+	require.NoError(t, worker.fetchAPOD(context.Background(), "", domain.FetchTriggerManual))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(succeeded)-succeededBefore)
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.FetchAttempts)-attemptsBefore, "one API request and one image download")
+	assert.Equal(t, float64(len(image)), testutil.ToFloat64(downloaded)-downloadedBefore)
+	assert.Equal(t, downloadsBefore+1, sampleCount(t, downloads))
+	assert.Equal(t, float64(finishedAt.Unix()), testutil.ToFloat64(metrics.LastSuccessfulFetch))
+
+	t.Run("already stored", func(t *testing.T) {
+		worker.Clock = &fakeClock{now: finishedAt.Add(time.Hour)}
+
+		err := worker.fetchAPOD(context.Background(), "", domain.FetchTriggerManual)
+		assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+
+		assert.Equal(t, 1.0, testutil.ToFloat64(skipped)-skippedBefore)
+		assert.Equal(t, float64(finishedAt.Unix()), testutil.ToFloat64(metrics.LastSuccessfulFetch), "only new entries count as successful")
+	})
+}
+
+// sampleCount returns how many observations the histogram holds.
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	t.Helper()
+
+	var metric dto.Metric
+	require.NoError(t, observer.(prometheus.Metric).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
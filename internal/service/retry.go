@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"math/rand"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/metrics"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type RetryPolicy struct {
+	MaxAttempts          int
+	BaseDelay            time.Duration
+	MaxDelay             time.Duration
+	Jitter               float64
+	RetryableStatusCodes map[int]bool
+	Logger               *zap.Logger
+
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func NewRetryPolicy(retryConfig config.RetryConfig, logger *zap.Logger) *RetryPolicy {
+	statusCodes := make(map[int]bool, len(retryConfig.RetryableStatusCodes))
+	for _, code := range retryConfig.RetryableStatusCodes {
+		statusCodes[code] = true
+	}
+
+	return &RetryPolicy{
+		MaxAttempts:          retryConfig.MaxAttempts,
+		BaseDelay:            retryConfig.BaseDelay,
+		MaxDelay:             retryConfig.MaxDelay,
+		Jitter:               retryConfig.Jitter,
+		RetryableStatusCodes: statusCodes,
+		Logger:               logger,
+		sleep:                sleepContext,
+	}
+}
+
+// Get requests url until it gets a response with a non-retryable status code or
+// runs out of attempts. The last response is returned as is, so callers keep
+// checking the status code themselves.
+func (p *RetryPolicy) Get(ctx context.Context, url string) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		fetchStatsFrom(ctx).addAttempt()
+		metrics.FetchAttempts.Inc()
+		resp, err := p.do(req, attempt)
+		if err == nil && !p.RetryableStatusCodes[resp.StatusCode] {
+			return resp, nil
+		}
+
+		if attempt >= p.MaxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		delay := p.backoff(attempt)
+		if err != nil {
+			p.Logger.Warn("Request failed, retrying", zap.Int("attempt", attempt), zap.Duration("delay", delay), zap.Error(err))
+		} else {
+			// Retrying before Retry-After has passed would only be rejected
+			// again, a longer wait than MaxDelay ends the retries instead.
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			if retryAfter > p.MaxDelay {
+				p.Logger.Warn("Retry-After exceeds the maximum retry delay, giving up", zap.Int("attempt", attempt), zap.Duration("retry_after", retryAfter), zap.Int("status_code", resp.StatusCode))
+				return resp, nil
+			}
+			delay = max(delay, retryAfter)
+			resp.Body.Close()
+
+			p.Logger.Warn("Request returned retryable status, retrying", zap.Int("attempt", attempt), zap.Duration("delay", delay), zap.Int("status_code", resp.StatusCode))
+		}
+
+		if err := p.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// do sends a single attempt under its own client span. Only host and path are
+// recorded, the query of NASA API requests carries the API key.
+func (p *RetryPolicy) do(req *http.Request, attempt int) (*http.Response, error) {
+	_, span := tracer().Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Hostname()),
+			attribute.String("url.path", req.URL.Path),
+			attribute.Int("http.request.resend_count", attempt-1),
+		),
+	)
+	defer span.End()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// The error quotes the request URL, keep the API key out of logs and fetch runs.
+		err = config.RedactURLError(err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		delay += time.Duration((2*rand.Float64() - 1) * p.Jitter * float64(delay))
+	}
+	return min(delay, p.MaxDelay)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return date.Sub(now)
+	}
+	return 0
+}
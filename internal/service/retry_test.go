@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"nasa-apod-app/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newTestRetryPolicy(maxAttempts int) (*RetryPolicy, *[]time.Duration) {
+	var delays []time.Duration
+	policy := NewRetryPolicy(config.RetryConfig{
+		MaxAttempts:          maxAttempts,
+		BaseDelay:            time.Second,
+		MaxDelay:             10 * time.Second,
+		RetryableStatusCodes: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	}, zap.NewNop())
+	policy.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return policy, &delays
+}
+
+func TestRetryPolicyGet(t *testing.T) {
+	t.Run("retries retryable status codes with backoff", func(t *testing.T) {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		policy, delays := newTestRetryPolicy(5)
+		resp, err := policy.Get(context.Background(), srv.URL)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 3, requests)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *delays)
+	})
+
+	t.Run("honors Retry-After", func(t *testing.T) {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests == 1 {
+				w.Header().Set("Retry-After", "7")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		policy, delays := newTestRetryPolicy(5)
+		resp, err := policy.Get(context.Background(), srv.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, []time.Duration{7 * time.Second}, *delays)
+	})
+
+	t.Run("gives up when Retry-After exceeds the maximum delay", func(t *testing.T) {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer srv.Close()
+
+		policy, delays := newTestRetryPolicy(5)
+		resp, err := policy.Get(context.Background(), srv.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, 1, requests)
+		assert.Empty(t, *delays)
+	})
+
+	t.Run("does not retry other status codes", func(t *testing.T) {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer srv.Close()
+
+		policy, delays := newTestRetryPolicy(5)
+		resp, err := policy.Get(context.Background(), srv.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, 1, requests)
+		assert.Empty(t, *delays)
+	})
+
+	t.Run("returns last response after max attempts", func(t *testing.T) {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		policy, _ := newTestRetryPolicy(3)
+		resp, err := policy.Get(context.Background(), srv.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, 3, requests)
+	})
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy, _ := newTestRetryPolicy(10)
+	assert.Equal(t, time.Second, policy.backoff(1))
+	assert.Equal(t, 4*time.Second, policy.backoff(3))
+	assert.Equal(t, 10*time.Second, policy.backoff(8))
+
+	policy.Jitter = 0.5
+	var longer, shorter bool
+	for i := 0; i < 100; i++ {
+		delay := policy.backoff(2)
+		assert.True(t, delay >= time.Second && delay <= 3*time.Second, delay)
+		longer = longer || delay > 2*time.Second
+		shorter = shorter || delay < 2*time.Second
+	}
+	assert.True(t, longer && shorter, "jitter lengthens and shortens delays")
+
+	for i := 0; i < 100; i++ {
+		assert.LessOrEqual(t, policy.backoff(8), 10*time.Second)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30", now))
+	assert.Equal(t, time.Minute, parseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/models"
+	"nasa-apod-app/internal/repository/memory"
+	"nasa-apod-app/internal/storage"
+	"nasa-apod-app/internal/storage/local"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type failingRepo struct {
+	*memory.ApodImagesRepository
+	saveErr error
+}
+
+func (r *failingRepo) Save(ctx context.Context, metadata domain.ApodImageMetaData, onConflict string) error {
+	if r.saveErr != nil {
+		return r.saveErr
+	}
+	return r.ApodImagesRepository.Save(ctx, metadata, onConflict)
+}
+
+type failingStorage struct {
+	storage.Storage
+	putErr    map[string]error
+	deleteErr error
+}
+
+func (s *failingStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) (storage.ObjectInfo, error) {
+	if err := s.putErr[key]; err != nil {
+		return storage.ObjectInfo{}, err
+	}
+	return s.Storage.Put(ctx, key, r, contentType)
+}
+
+func (s *failingStorage) Delete(ctx context.Context, key string) error {
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	return s.Storage.Delete(ctx, key)
+}
+
+func TestSaveAPODDataFailures(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(encodeTestImage(t, "png", 2, 2))
+	})
+	mux.HandleFunc("/hd", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(encodeTestImage(t, "png", 4, 4))
+	})
+	mux.HandleFunc("/truncated", func(w http.ResponseWriter, r *http.Request) {
+		// Promise more than is sent, the client sees an unexpected EOF halfway through.
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("trunc"))
+	})
+
+	apodData := models.APODResponse{
+		Date:      "2024-01-01",
+		MediaType: domain.MediaTypeImage,
+		URL:       srv.URL + "/image",
+		HDURL:     srv.URL + "/hd",
+	}
+
+	newService := func(t *testing.T) (*ApodImagesService, *failingRepo, *failingStorage) {
+		repo := &failingRepo{ApodImagesRepository: memory.NewMemoryRepository()}
+		imageStorage := &failingStorage{Storage: local.NewLocalStorage(t.TempDir())}
+		apodService := NewApodImagesService(zap.NewNop(), repo, imageStorage, config.WorkerConfig{DownloadHDImages: true})
+		return apodService, repo, imageStorage
+	}
+
+	assertNothingStored := func(t *testing.T, repo *failingRepo, imageStorage *failingStorage) {
+		t.Helper()
+
+		exists, err := repo.ExistsByDate(context.Background(), apodData.Date)
+		assert.NoError(t, err)
+		assert.False(t, exists)
+
+		objects, err := imageStorage.List(context.Background(), "")
+		assert.NoError(t, err)
+		assert.Empty(t, objects)
+	}
+
+	t.Run("download interrupted halfway", func(t *testing.T) {
+		apodService, repo, imageStorage := newService(t)
+
+		data := apodData
+		data.URL = srv.URL + "/truncated"
+		err := apodService.SaveAPODData(context.Background(), data)
+		assert.Error(t, err)
+		assertNothingStored(t, repo, imageStorage)
+	})
+
+	t.Run("storage write fails", func(t *testing.T) {
+		apodService, repo, imageStorage := newService(t)
+		imageStorage.putErr = map[string]error{storageKey(apodData.Date, domain.AssetVariantStandard, "image/png"): errors.New("disk full")}
+
+		err := apodService.SaveAPODData(context.Background(), apodData)
+		assert.Error(t, err)
+		assertNothingStored(t, repo, imageStorage)
+	})
+
+	t.Run("database insert fails", func(t *testing.T) {
+		apodService, repo, imageStorage := newService(t)
+		repo.saveErr = errors.New("connection refused")
+
+		err := apodService.SaveAPODData(context.Background(), apodData)
+		assert.ErrorIs(t, err, repo.saveErr)
+		assertNothingStored(t, repo, imageStorage)
+	})
+
+	t.Run("cleanup failure still reports the insert error", func(t *testing.T) {
+		apodService, repo, imageStorage := newService(t)
+		repo.saveErr = errors.New("connection refused")
+		imageStorage.deleteErr = errors.New("permission denied")
+
+		err := apodService.SaveAPODData(context.Background(), apodData)
+		assert.ErrorIs(t, err, repo.saveErr)
+	})
+
+	t.Run("optional HD failure keeps the standard image", func(t *testing.T) {
+		apodService, repo, imageStorage := newService(t)
+		imageStorage.putErr = map[string]error{storageKey(apodData.Date, domain.AssetVariantHD, "image/png"): errors.New("disk full")}
+
+		err := apodService.SaveAPODData(context.Background(), apodData)
+		assert.NoError(t, err)
+
+		saved := storedImage(t, repo, apodData.Date)
+		assert.Len(t, saved.Assets, 1)
+
+		objects, err := imageStorage.List(context.Background(), "")
+		assert.NoError(t, err)
+		assert.Len(t, objects, 1)
+		assert.Equal(t, saved.StorageKey, objects[0].Key)
+	})
+}
+
+func TestSaveAPODDataConflictPolicy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(encodeTestImage(t, "png", 2, 2))
+	}))
+	defer srv.Close()
+
+	stored := models.APODResponse{Date: "2024-01-01", Title: "Original", MediaType: domain.MediaTypeImage, URL: srv.URL}
+	updated := stored
+	updated.Title = "Corrected"
+
+	newService := func(t *testing.T, policy string) (*ApodImagesService, *memory.ApodImagesRepository) {
+		repo := memory.NewMemoryRepository()
+		apodService := NewApodImagesService(zap.NewNop(), repo, local.NewLocalStorage(t.TempDir()), config.WorkerConfig{ConflictPolicy: policy})
+		assert.NoError(t, apodService.SaveAPODData(context.Background(), stored))
+		return apodService, repo
+	}
+
+	t.Run("skip returns a typed error", func(t *testing.T) {
+		apodService, repo := newService(t, domain.ConflictPolicySkip)
+
+		err := apodService.SaveAPODData(context.Background(), updated)
+		var existsErr *domain.AlreadyExistsError
+		assert.ErrorAs(t, err, &existsErr)
+		assert.Equal(t, "2024-01-01", existsErr.Date)
+		assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+		assert.Equal(t, "Original", storedImage(t, repo, "2024-01-01").Title)
+	})
+
+	t.Run("refresh overwrites metadata and keeps assets", func(t *testing.T) {
+		apodService, repo := newService(t, domain.ConflictPolicyRefresh)
+		before := storedImage(t, repo, "2024-01-01")
+
+		err := apodService.SaveAPODData(context.Background(), updated)
+		assert.NoError(t, err)
+
+		after := storedImage(t, repo, "2024-01-01")
+		assert.Equal(t, "Corrected", after.Title)
+		assert.Equal(t, before.StorageKey, after.StorageKey)
+		assert.Equal(t, before.Assets, after.Assets)
+	})
+
+	t.Run("losing an insert race keeps the winner's files", func(t *testing.T) {
+		repo := &failingRepo{ApodImagesRepository: memory.NewMemoryRepository(), saveErr: &domain.AlreadyExistsError{Date: stored.Date}}
+		imageStorage := local.NewLocalStorage(t.TempDir())
+		apodService := NewApodImagesService(zap.NewNop(), repo, imageStorage, config.WorkerConfig{})
+
+		err := apodService.SaveAPODData(context.Background(), stored)
+		assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+
+		_, err = imageStorage.Stat(context.Background(), storageKey(stored.Date, domain.AssetVariantStandard, "image/png"))
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Schedule fires at every time matched by any of its standard cron
+// expressions, evaluated in its location so runs follow local DST changes.
+type Schedule struct {
+	specs    []cron.Schedule
+	location *time.Location
+}
+
+func NewSchedule(expressions []string, location *time.Location) (*Schedule, error) {
+	if len(expressions) == 0 {
+		return nil, fmt.Errorf("at least one cron expression is required")
+	}
+
+	if location == nil {
+		location = time.Local
+	}
+
+	schedule := &Schedule{location: location}
+	for _, expression := range expressions {
+		spec, err := cron.ParseStandard(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+		}
+
+		if specSchedule, ok := spec.(*cron.SpecSchedule); ok {
+			specSchedule.Location = location
+		}
+		schedule.specs = append(schedule.specs, spec)
+	}
+
+	return schedule, nil
+}
+
+func DailySchedule(runTime time.Time, location *time.Location) *Schedule {
+	schedule, _ := NewSchedule([]string{fmt.Sprintf("%d %d * * *", runTime.Minute(), runTime.Hour())}, location)
+	return schedule
+}
+
+func (s *Schedule) Next(now time.Time) time.Time {
+	var next time.Time
+	for _, spec := range s.specs {
+		candidate := spec.Next(now)
+		if next.IsZero() || candidate.Before(next) {
+			next = candidate
+		}
+	}
+	return next
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/models"
+	"nasa-apod-app/internal/repository/memory"
+	"nasa-apod-app/internal/storage/local"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestScheduleNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	t.Run("keeps wall clock time across DST start", func(t *testing.T) {
+		schedule, err := NewSchedule([]string{"0 19 * * *"}, newYork)
+		assert.NoError(t, err)
+
+		next := schedule.Next(time.Date(2024, 3, 9, 20, 0, 0, 0, newYork))
+		assert.Equal(t, time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC), next.UTC())
+	})
+
+	t.Run("keeps wall clock time across DST end", func(t *testing.T) {
+		schedule, err := NewSchedule([]string{"0 19 * * *"}, newYork)
+		assert.NoError(t, err)
+
+		next := schedule.Next(time.Date(2024, 11, 2, 20, 0, 0, 0, newYork))
+		assert.Equal(t, time.Date(2024, 11, 4, 0, 0, 0, 0, time.UTC), next.UTC())
+	})
+
+	t.Run("picks the earliest of several expressions", func(t *testing.T) {
+		schedule, err := NewSchedule([]string{"0 19 * * *", "30 6 * * *"}, time.UTC)
+		assert.NoError(t, err)
+
+		assert.Equal(t, time.Date(2024, 1, 2, 6, 30, 0, 0, time.UTC), schedule.Next(time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)))
+		assert.Equal(t, time.Date(2024, 1, 2, 19, 0, 0, 0, time.UTC), schedule.Next(time.Date(2024, 1, 2, 7, 0, 0, 0, time.UTC)))
+	})
+
+	t.Run("invalid expression", func(t *testing.T) {
+		_, err := NewSchedule([]string{"0 25 * * *"}, time.UTC)
+		assert.Error(t, err)
+	})
+
+	t.Run("no expressions", func(t *testing.T) {
+		_, err := NewSchedule(nil, time.UTC)
+		assert.Error(t, err)
+	})
+}
+
+func TestFetchAPODWithinDay(t *testing.T) {
+	newWorker := func(t *testing.T, apiURL string, clock Clock) (*APODWorker, *memory.ApodImagesRepository) {
+		logger := zap.NewNop()
+		repo := memory.NewMemoryRepository()
+		schedule, err := NewSchedule([]string{"0 9 * * *"}, time.UTC)
+		assert.NoError(t, err)
+
+		return &APODWorker{
+			ApodService:      NewApodImagesService(logger, repo, local.NewLocalStorage(t.TempDir()), config.WorkerConfig{}),
+			ApodURL:          apiURL,
+			Schedule:         schedule,
+			Clock:            clock,
+			MissedFetchRetry: time.Hour,
+			Logger:           logger,
+		}, repo
+	}
+
+	t.Run("retries until the fetch succeeds", func(t *testing.T) {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests < 3 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(models.APODResponse{Date: "2024-01-01", MediaType: "video"})
+		}))
+		defer srv.Close()
+
+		clock := &fakeClock{now: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+		worker, repo := newWorker(t, srv.URL, clock)
+
+		worker.fetchAPODWithinDay(context.Background())
+		assert.Equal(t, 3, requests)
+		assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), clock.now)
+		assert.Equal(t, "2024-01-01", storedImage(t, repo, "2024-01-01").Date)
+	})
+
+	t.Run("gives up before the next scheduled run", func(t *testing.T) {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer srv.Close()
+
+		clock := &fakeClock{now: time.Date(2024, 1, 1, 6, 30, 0, 0, time.UTC)}
+		worker, _ := newWorker(t, srv.URL, clock)
+
+		worker.fetchAPODWithinDay(context.Background())
+		assert.Equal(t, 3, requests)
+		assert.Equal(t, time.Date(2024, 1, 1, 8, 30, 0, 0, time.UTC), clock.now)
+	})
+}
@@ -1,74 +1,183 @@
 package service
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"image"
 	"io"
+	"nasa-apod-app/internal/config"
 	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/metrics"
 	"nasa-apod-app/internal/models"
+	"nasa-apod-app/internal/storage"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type ApodImagesRepo interface {
-	GetAllImages(ctx context.Context) ([]domain.ApodImageMetaData, error)
+	GetAllImages(ctx context.Context, filter domain.ApodImagesFilter) ([]domain.ApodImageMetaData, error)
 	GetImageByDate(ctx context.Context, date string) (*domain.ApodImageMetaData, error)
-	ExistsByDate(date string) (bool, error)
-	Save(metadata domain.ApodImageMetaData) error
+	Search(ctx context.Context, search domain.ApodSearchQuery) ([]domain.ApodSearchResult, error)
+	ExistsByDate(ctx context.Context, date string) (bool, error)
+	Save(ctx context.Context, metadata domain.ApodImageMetaData, onConflict string) error
 }
 
 type ApodImagesService struct {
 	logger     *zap.Logger
 	repository ApodImagesRepo
+	storage    storage.Storage
+	retry      *RetryPolicy
+	downloadHD bool
+	onConflict string
+	saving     dateLocks
+	variants   []imageVariant
+	processing chan struct{}
 }
 
 var (
-	ErrInvalidDate    = fmt.Errorf("invalid date format provided. use YYYY-MM-DD")
-	ErrImageNotFound  = fmt.Errorf("image not found")
-	ErrImagesNotFound = fmt.Errorf("images not found")
+	ErrInvalidDate      = fmt.Errorf("invalid date format provided. use YYYY-MM-DD")
+	ErrImageNotFound    = fmt.Errorf("image not found")
+	ErrImagesNotFound   = fmt.Errorf("images not found")
+	ErrInvalidMediaType = fmt.Errorf("invalid media type provided. use image, video or other")
+	ErrAssetNotFound    = fmt.Errorf("image asset not found")
+	ErrInvalidCursor    = fmt.Errorf("invalid cursor provided")
+	ErrInvalidOrder     = fmt.Errorf("invalid sort order provided. use asc or desc")
+	ErrEmptySearchQuery = fmt.Errorf("search query must not be empty")
+	ErrInvalidLimit     = fmt.Errorf("invalid limit provided. use a value between 1 and %d", MaxPageLimit)
 )
 
-func NewApodImagesService(logger *zap.Logger, repository ApodImagesRepo) *ApodImagesService {
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+func NewApodImagesService(logger *zap.Logger, repository ApodImagesRepo, imageStorage storage.Storage, workerConfig config.WorkerConfig) *ApodImagesService {
 	return &ApodImagesService{
 		logger:     logger,
 		repository: repository,
+		storage:    imageStorage,
+		retry:      NewRetryPolicy(workerConfig.Retry, logger),
+		downloadHD: workerConfig.DownloadHDImages,
+		onConflict: conflictPolicy(workerConfig.ConflictPolicy, logger),
+		variants:   parseImageVariants(workerConfig.ImageVariants, logger),
+		processing: make(chan struct{}, max(1, workerConfig.ImageProcessingWorkers)),
 	}
 }
 
-func (s *ApodImagesService) SaveAPODData(apodData models.APODResponse) error {
-	exists, err := s.repository.ExistsByDate(apodData.Date)
+func conflictPolicy(policy string, logger *zap.Logger) string {
+	switch policy {
+	case domain.ConflictPolicySkip, domain.ConflictPolicyRefresh:
+		return policy
+	case "":
+		return domain.ConflictPolicySkip
+	default:
+		logger.Warn("Unknown conflict policy, skipping already stored dates", zap.String("policy", policy))
+		return domain.ConflictPolicySkip
+	}
+}
+
+func (s *ApodImagesService) SaveAPODData(ctx context.Context, apodData models.APODResponse) (err error) {
+	ctx, span := tracer().Start(ctx, "ApodImagesService.SaveAPODData", trace.WithAttributes(attribute.String("apod.date", apodData.Date), attribute.String("apod.media_type", apodData.MediaType)))
+	defer func() { endSpan(span, err) }()
+
+	unlock := s.saving.lock(apodData.Date)
+	defer unlock()
+
+	exists, err := s.repository.ExistsByDate(ctx, apodData.Date)
 	if err != nil {
 		s.logger.Error("Failed to check if APOD data exists", zap.Error(err))
 		return fmt.Errorf("failed to check if APOD data exists: %w", err)
 	}
 
-	if exists {
+	if exists && s.onConflict != domain.ConflictPolicyRefresh {
 		s.logger.Info("APOD data already exists", zap.String("date", apodData.Date))
-		return fmt.Errorf("APOD for today was already saved")
+		return &domain.AlreadyExistsError{Date: apodData.Date}
 	}
 
-	s.logger.Info("Saving APOD data", zap.String("date", apodData.Date))
+	mediaType := normalizeMediaType(apodData.MediaType)
+	s.logger.Info("Saving APOD data", zap.String("date", apodData.Date), zap.String("media_type", mediaType))
 
-	imagePath, err := s.downloadImage(apodData.URL, apodData.Date)
-	if err != nil {
-		s.logger.Error("Failed to download image", zap.Error(err))
-		return fmt.Errorf("failed to download image: %w", err)
+	metadata := domain.ApodImageMetaData{
+		Title:          apodData.Title,
+		Explanation:    apodData.Explanation,
+		Date:           apodData.Date,
+		Copyright:      apodData.Copyright,
+		MediaType:      mediaType,
+		URL:            apodData.URL,
+		HDURL:          apodData.HDURL,
+		ThumbnailURL:   apodData.ThumbnailURL,
+		ServiceVersion: apodData.ServiceVersion,
 	}
 
-	metadata := domain.ApodImageMetaData{
-		Title:                 apodData.Title,
-		Explanation:           apodData.Explanation,
-		Date:                  apodData.Date,
-		Copyright:             apodData.Copyright,
-		LocalStorageImagePath: imagePath,
+	if exists {
+		// Refreshing only rewrites the metadata, stored files and their asset rows stay untouched.
+		if err := s.repository.Save(ctx, metadata, domain.ConflictPolicyRefresh); err != nil {
+			s.logger.Error("Failed to refresh APOD data", zap.Error(err))
+			return err
+		}
+
+		s.logger.Info("APOD data refreshed successfully", zap.String("date", apodData.Date))
+		return nil
 	}
 
-	err = s.repository.Save(metadata)
+	// Only image days point to a file we can store, videos and other media are kept as embeddable URLs.
+	if mediaType == domain.MediaTypeImage {
+		asset, err := s.downloadImage(ctx, apodData.URL, apodData.Date, domain.AssetVariantStandard)
+		if err != nil {
+			s.logger.Error("Failed to download image", zap.Error(err))
+			return fmt.Errorf("failed to download image: %w", err)
+		}
+		metadata.StorageKey = asset.StorageKey
+		metadata.Assets = append(metadata.Assets, asset)
+
+		if s.downloadHD && apodData.HDURL != "" {
+			hdAsset, err := s.downloadImage(ctx, apodData.HDURL, apodData.Date, domain.AssetVariantHD)
+			if err != nil {
+				// HD is optional, the day is still stored with its standard resolution image.
+				s.logger.Warn("Failed to download HD image", zap.String("date", apodData.Date), zap.Error(err))
+			} else {
+				metadata.Assets = append(metadata.Assets, hdAsset)
+			}
+		}
+	} else if apodData.ThumbnailURL != "" {
+		thumbnailAsset, err := s.downloadImage(ctx, apodData.ThumbnailURL, apodData.Date, domain.AssetVariantThumbnail)
+		if err != nil {
+			// The video is still embeddable without a preview image.
+			s.logger.Warn("Failed to download thumbnail", zap.String("date", apodData.Date), zap.Error(err))
+		} else {
+			metadata.Assets = append(metadata.Assets, thumbnailAsset)
+		}
+	}
+
+	metadata.Assets = append(metadata.Assets, s.generateVariants(ctx, apodData.Date, metadata.Assets)...)
+
+	// Optional downloads only warn on failure, so a shutdown in the middle of
+	// them must not leave a half stored day behind.
+	if err := ctx.Err(); err != nil {
+		s.deleteAssets(metadata.Assets)
+		return fmt.Errorf("saving APOD data aborted: %w", err)
+	}
+
+	err = s.repository.Save(ctx, metadata, s.onConflict)
+	if errors.Is(err, domain.ErrAlreadyExists) {
+		// Another writer stored the date in the meantime. Storage keys derive from
+		// the date, so the files just written are the ones its row points to.
+		s.logger.Info("APOD data already exists", zap.String("date", apodData.Date))
+		return err
+	}
 	if err != nil {
 		s.logger.Error("Failed to save APOD data", zap.Error(err))
+		// The insert is transactional, so without a row nothing references the stored files.
+		s.deleteAssets(metadata.Assets)
 		return err
 	}
 
@@ -76,66 +185,172 @@ func (s *ApodImagesService) SaveAPODData(apodData models.APODResponse) error {
 	return nil
 }
 
-func (s *ApodImagesService) downloadImage(imageURL, date string) (string, error) {
-	s.logger.Info("Downloading image", zap.String("url", imageURL), zap.String("date", date))
+func (s *ApodImagesService) deleteAssets(assets []domain.ApodAsset) {
+	for _, asset := range assets {
+		// The caller's context is usually already done here, cleanup has to run regardless.
+		if err := s.storage.Delete(context.Background(), asset.StorageKey); err != nil {
+			s.logger.Warn("Failed to delete stored asset", zap.String("storage_key", asset.StorageKey), zap.Error(err))
+		}
+	}
+}
+
+// downloadImage streams imageURL into storage and describes what was stored.
+// The storage key extension follows the sniffed content type, not the URL.
+func (s *ApodImagesService) downloadImage(ctx context.Context, imageURL, date, variant string) (asset domain.ApodAsset, err error) {
+	ctx, span := tracer().Start(ctx, "ApodImagesService.downloadImage", trace.WithAttributes(attribute.String("apod.date", date), attribute.String("apod.variant", variant)))
+	defer func() { endSpan(span, err) }()
+
+	s.logger.Info("Downloading image", zap.String("url", imageURL), zap.String("date", date), zap.String("variant", variant))
+	start := time.Now()
 
-	resp, err := http.Get(imageURL)
+	resp, err := s.retry.Get(ctx, imageURL)
 	if err != nil {
 		s.logger.Error("Failed to download image", zap.Error(err))
-		return "", fmt.Errorf("failed to download image from %s: %w", imageURL, err)
+		return domain.ApodAsset{}, fmt.Errorf("failed to download image from %s: %w", imageURL, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		s.logger.Error("Non-200 response code received", zap.Int("status_code", resp.StatusCode))
-		return "", fmt.Errorf("received non-200 response code while downloading image: %d", resp.StatusCode)
+		return domain.ApodAsset{}, fmt.Errorf("received non-200 response code while downloading image: %d", resp.StatusCode)
 	}
 
-	storageDir := "./storage/apod"
-	if err := os.MkdirAll(storageDir, os.ModePerm); err != nil {
-		s.logger.Error("Failed to create storage directory", zap.Error(err))
-		return "", fmt.Errorf("failed to create storage directory: %w", err)
+	body := bufio.NewReaderSize(resp.Body, sniffLen)
+	head, _ := body.Peek(sniffLen)
+	contentType := detectContentType(head, resp.Header.Get("Content-Type"))
+	key := storageKey(date, variant, contentType)
+
+	// Stream straight from the response body so large HD images are never held
+	// in memory, hashing on the way through.
+	hash := sha256.New()
+	info, err := s.storage.Put(ctx, key, io.TeeReader(body, hash), contentType)
+	if err != nil {
+		s.logger.Error("Failed to store image", zap.Error(err))
+		return domain.ApodAsset{}, fmt.Errorf("failed to store image: %w", err)
 	}
+	fetchStatsFrom(ctx).addBytes(info.Size)
+	metrics.DownloadedBytes.WithLabelValues(variant).Add(float64(info.Size))
+	metrics.DownloadDuration.WithLabelValues(variant).Observe(time.Since(start).Seconds())
 
-	fileName := fmt.Sprintf("%s.jpg", date)
-	filePath := filepath.Join(storageDir, fileName)
+	asset = domain.ApodAsset{
+		Variant:     variant,
+		SourceURL:   imageURL,
+		StorageKey:  key,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		Size:        info.Size,
+		ContentType: contentType,
+	}
 
-	file, err := os.Create(filePath)
+	width, height, err := s.imageDimensions(ctx, key)
 	if err != nil {
-		s.logger.Error("Failed to create image file", zap.Error(err))
-		return "", fmt.Errorf("failed to create image file: %w", err)
+		s.logger.Warn("Failed to read image dimensions", zap.String("storage_key", key), zap.Error(err))
 	}
-	defer file.Close()
+	asset.Width, asset.Height = width, height
 
-	_, err = io.Copy(file, resp.Body)
+	s.logger.Info("Image saved successfully", zap.String("storage_key", key), zap.String("content_type", contentType), zap.Int64("size", info.Size))
+	return asset, nil
+}
+
+func (s *ApodImagesService) imageDimensions(ctx context.Context, key string) (int, int, error) {
+	object, _, err := s.storage.Get(ctx, key)
 	if err != nil {
-		s.logger.Error("Failed to save image to file", zap.Error(err))
-		return "", fmt.Errorf("failed to save image to file: %w", err)
+		return 0, 0, err
 	}
+	defer object.Close()
 
-	s.logger.Info("Image saved successfully", zap.String("file_path", filePath))
-	return filePath, nil
+	// DecodeConfig only reads the image header, not the pixels.
+	config, _, err := image.DecodeConfig(bufio.NewReader(object))
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
 }
 
-func (s *ApodImagesService) GetImageByDate(ctx context.Context, date string) (*domain.ApodImageMetaData, error) {
-	_, err := time.Parse("2006-01-02", date)
+func (s *ApodImagesService) GetImageByDate(ctx context.Context, date string) (image *domain.ApodImageMetaData, err error) {
+	ctx, span := tracer().Start(ctx, "ApodImagesService.GetImageByDate", trace.WithAttributes(attribute.String("apod.date", date)))
+	defer func() { endSpan(span, err) }()
+
+	_, err = time.Parse(apodDateLayout, date)
 	if err != nil {
 		s.logger.Error("Invalid date format", zap.Error(err))
 		return nil, ErrInvalidDate
 	}
 
-	image, err := s.repository.GetImageByDate(ctx, date)
+	image, err = s.repository.GetImageByDate(ctx, date)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, ErrImageNotFound
+	}
 	if err != nil {
 		s.logger.Error("Failed to fetch APOD image by date", zap.Error(err))
-		return nil, ErrImageNotFound
+		return nil, fmt.Errorf("failed to fetch APOD image: %w", err)
 	}
 
 	s.logger.Info("APOD image fetched successfully", zap.String("date", date))
 	return image, nil
 }
 
-func (s *ApodImagesService) GetAllImages(ctx context.Context) ([]domain.ApodImageMetaData, error) {
-	images, err := s.repository.GetAllImages(ctx)
+func (s *ApodImagesService) GetImageContent(ctx context.Context, date, variant string) (content io.ReadSeekCloser, info storage.ObjectInfo, err error) {
+	ctx, span := tracer().Start(ctx, "ApodImagesService.GetImageContent", trace.WithAttributes(attribute.String("apod.date", date), attribute.String("apod.variant", variant)))
+	defer func() { endSpan(span, err) }()
+
+	image, err := s.GetImageByDate(ctx, date)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+
+	asset, ok := findAsset(image.Assets, variant)
+	if !ok && variant == domain.AssetVariantThumbnail {
+		// Image days have no dedicated thumbnail, the standard image is the closest match.
+		asset, ok = findAsset(image.Assets, domain.AssetVariantStandard)
+	}
+
+	imageVariant, generated := s.findVariant(variant)
+	if !ok && generated {
+		if asset, err = s.regenerateVariant(ctx, image, imageVariant); err == nil {
+			ok = true
+		} else {
+			s.logger.Error("Failed to regenerate image variant", zap.String("date", date), zap.String("variant", variant), zap.Error(err))
+		}
+	}
+
+	if !ok {
+		s.logger.Error("APOD image asset not found", zap.String("date", date), zap.String("variant", variant))
+		return nil, storage.ObjectInfo{}, ErrAssetNotFound
+	}
+
+	content, info, err = s.storage.Get(ctx, asset.StorageKey)
+	if errors.Is(err, storage.ErrObjectNotFound) && generated {
+		// Generated variants can always be recreated from the original image.
+		if asset, err = s.regenerateVariant(ctx, image, imageVariant); err == nil {
+			content, info, err = s.storage.Get(ctx, asset.StorageKey)
+		}
+	}
+	if err != nil {
+		s.logger.Error("Failed to open APOD image asset", zap.String("storage_key", asset.StorageKey), zap.Error(err))
+
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return nil, storage.ObjectInfo{}, ErrAssetNotFound
+		}
+		return nil, storage.ObjectInfo{}, err
+	}
+
+	return content, info, nil
+}
+
+func (s *ApodImagesService) GetAllImages(ctx context.Context, filter domain.ApodImagesFilter) (page *domain.ApodImagesPage, err error) {
+	ctx, span := tracer().Start(ctx, "ApodImagesService.GetAllImages")
+	defer func() { endSpan(span, err) }()
+
+	filter, err = s.normalizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// One extra row tells whether there is a next page without a separate count query.
+	repoFilter := filter
+	repoFilter.Limit = filter.Limit + 1
+
+	images, err := s.repository.GetAllImages(ctx, repoFilter)
 	if err != nil {
 		s.logger.Error("Failed to fetch all APOD images", zap.Error(err))
 		return nil, err
@@ -146,6 +361,140 @@ func (s *ApodImagesService) GetAllImages(ctx context.Context) ([]domain.ApodImag
 		return nil, ErrImagesNotFound
 	}
 
+	page = &domain.ApodImagesPage{Images: images}
+	if len(images) > filter.Limit {
+		page.Images = images[:filter.Limit]
+		page.NextCursor = page.Images[filter.Limit-1].Date
+	}
+
 	s.logger.Info("All APOD images fetched successfully")
-	return images, nil
+	return page, nil
+}
+
+func (s *ApodImagesService) Search(ctx context.Context, search domain.ApodSearchQuery) (page *domain.ApodSearchPage, err error) {
+	ctx, span := tracer().Start(ctx, "ApodImagesService.Search")
+	defer func() { endSpan(span, err) }()
+
+	search.Query = strings.TrimSpace(search.Query)
+	if search.Query == "" {
+		s.logger.Error("Empty search query")
+		return nil, ErrEmptySearchQuery
+	}
+
+	if search.Limit == 0 {
+		search.Limit = DefaultPageLimit
+	}
+	if search.Limit < 0 || search.Limit > MaxPageLimit {
+		s.logger.Error("Invalid page limit", zap.Int("limit", search.Limit))
+		return nil, ErrInvalidLimit
+	}
+
+	// Ranked results have no stable key to seek on, so the search cursor is an offset.
+	if search.Cursor != "" {
+		offset, err := strconv.Atoi(search.Cursor)
+		if err != nil || offset < 0 {
+			s.logger.Error("Invalid cursor", zap.String("cursor", search.Cursor))
+			return nil, ErrInvalidCursor
+		}
+		search.Offset = offset
+	}
+
+	repoSearch := search
+	repoSearch.Limit = search.Limit + 1
+
+	results, err := s.repository.Search(ctx, repoSearch)
+	if err != nil {
+		s.logger.Error("Failed to search APOD images", zap.Error(err))
+		return nil, err
+	}
+
+	page = &domain.ApodSearchPage{Results: results}
+	if len(results) > search.Limit {
+		page.Results = results[:search.Limit]
+		page.NextCursor = strconv.Itoa(search.Offset + search.Limit)
+	}
+	if page.Results == nil {
+		page.Results = []domain.ApodSearchResult{}
+	}
+
+	s.logger.Info("APOD images searched successfully", zap.String("query", search.Query), zap.Int("results", len(page.Results)))
+	return page, nil
+}
+
+func (s *ApodImagesService) normalizeFilter(filter domain.ApodImagesFilter) (domain.ApodImagesFilter, error) {
+	if filter.MediaType != "" && !isKnownMediaType(filter.MediaType) {
+		s.logger.Error("Invalid media type", zap.String("media_type", filter.MediaType))
+		return filter, ErrInvalidMediaType
+	}
+
+	for _, date := range []string{filter.From, filter.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(apodDateLayout, date); err != nil {
+			s.logger.Error("Invalid date format", zap.Error(err))
+			return filter, ErrInvalidDate
+		}
+	}
+
+	if filter.Cursor != "" {
+		if _, err := time.Parse(apodDateLayout, filter.Cursor); err != nil {
+			s.logger.Error("Invalid cursor", zap.Error(err))
+			return filter, ErrInvalidCursor
+		}
+	}
+
+	switch filter.Order {
+	case "":
+		filter.Order = domain.SortOrderDesc
+	case domain.SortOrderAsc, domain.SortOrderDesc:
+	default:
+		s.logger.Error("Invalid sort order", zap.String("order", filter.Order))
+		return filter, ErrInvalidOrder
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = DefaultPageLimit
+	}
+	if filter.Limit < 0 || filter.Limit > MaxPageLimit {
+		s.logger.Error("Invalid page limit", zap.Int("limit", filter.Limit))
+		return filter, ErrInvalidLimit
+	}
+
+	return filter, nil
+}
+
+func findAsset(assets []domain.ApodAsset, variant string) (domain.ApodAsset, bool) {
+	for _, asset := range assets {
+		if asset.Variant == variant {
+			return asset, true
+		}
+	}
+	return domain.ApodAsset{}, false
+}
+
+func normalizeMediaType(mediaType string) string {
+	if mediaType == "" {
+		return domain.MediaTypeImage
+	}
+	if !isKnownMediaType(mediaType) {
+		return domain.MediaTypeOther
+	}
+	return mediaType
+}
+
+func isKnownMediaType(mediaType string) bool {
+	switch mediaType {
+	case domain.MediaTypeImage, domain.MediaTypeVideo, domain.MediaTypeOther:
+		return true
+	}
+	return false
+}
+
+func storageKey(date, variant, contentType string) string {
+	extension := extensionForContentType(contentType)
+	if variant == domain.AssetVariantStandard {
+		return fmt.Sprintf("apod/%s%s", date, extension)
+	}
+	return fmt.Sprintf("apod/%s_%s%s", date, variant, extension)
 }
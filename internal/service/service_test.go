@@ -2,28 +2,39 @@ package service
 
 import (
 	"context"
-	"errors"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"nasa-apod-app/internal/config"
 	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/models"
+	"nasa-apod-app/internal/repository/memory"
+	"nasa-apod-app/internal/storage/local"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
 func TestGetImageByDate(t *testing.T) {
 	logger := zap.NewNop()
-	repo := NewInMemoryApodImagesRepo()
-	apodService := NewApodImagesService(logger, repo)
+	repo := memory.NewMemoryRepository()
+	apodService := NewApodImagesService(logger, repo, local.NewLocalStorage(t.TempDir()), config.WorkerConfig{})
 
 	date := "2023-09-18"
 	image := domain.ApodImageMetaData{Date: date, Title: "Test"}
 
-	repo.Save(image)
+	repo.Save(context.Background(), image, domain.ConflictPolicySkip)
 
 	t.Run("successfully retrieves APOD image by date", func(t *testing.T) {
 		img, err := apodService.GetImageByDate(context.Background(), date)
 		assert.NoError(t, err)
-		assert.Equal(t, &image, img)
+		assert.Equal(t, date, img.Date)
+		assert.Equal(t, "Test", img.Title)
 	})
 
 	t.Run("invalid date format", func(t *testing.T) {
@@ -39,64 +50,270 @@ func TestGetImageByDate(t *testing.T) {
 	})
 }
 
+func TestSaveAPODDataVideo(t *testing.T) {
+	logger := zap.NewNop()
+	repo := memory.NewMemoryRepository()
+	apodService := NewApodImagesService(logger, repo, local.NewLocalStorage(t.TempDir()), config.WorkerConfig{})
+
+	err := apodService.SaveAPODData(context.Background(), models.APODResponse{
+		Date:         "2023-09-20",
+		Title:        "Video",
+		MediaType:    domain.MediaTypeVideo,
+		URL:          "https://www.youtube.com/embed/abc",
+		ThumbnailURL: "https://img.youtube.com/vi/abc/0.jpg",
+	})
+	assert.NoError(t, err)
+
+	saved := storedImage(t, repo, "2023-09-20")
+	assert.Equal(t, domain.MediaTypeVideo, saved.MediaType)
+	assert.Equal(t, "https://www.youtube.com/embed/abc", saved.URL)
+	assert.Equal(t, "https://img.youtube.com/vi/abc/0.jpg", saved.ThumbnailURL)
+	assert.Empty(t, saved.StorageKey)
+}
+
+func TestSaveAPODDataHD(t *testing.T) {
+	standardImage := encodeTestImage(t, "jpeg", 4, 3)
+	hdImage := encodeTestImage(t, "jpeg", 8, 6)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing_hd.jpg":
+			w.WriteHeader(http.StatusNotFound)
+		case "/hd.jpg":
+			w.Write(hdImage)
+		default:
+			w.Write(standardImage)
+		}
+	}))
+	defer srv.Close()
+
+	logger := zap.NewNop()
+	repo := memory.NewMemoryRepository()
+	imageStorage := local.NewLocalStorage(t.TempDir())
+	apodService := NewApodImagesService(logger, repo, imageStorage, config.WorkerConfig{DownloadHDImages: true})
+
+	t.Run("stores standard and HD assets", func(t *testing.T) {
+		err := apodService.SaveAPODData(context.Background(), models.APODResponse{
+			Date:  "2023-09-21",
+			URL:   srv.URL + "/standard.jpg",
+			HDURL: srv.URL + "/hd.jpg",
+		})
+		assert.NoError(t, err)
+
+		saved := storedImage(t, repo, "2023-09-21")
+		assert.Len(t, saved.Assets, 2)
+		assert.Equal(t, domain.AssetVariantStandard, saved.Assets[0].Variant)
+		assert.Equal(t, domain.AssetVariantHD, saved.Assets[1].Variant)
+
+		hd := saved.Assets[1]
+		assert.Equal(t, "apod/2023-09-21_hd.jpg", hd.StorageKey)
+		assert.Equal(t, "image/jpeg", hd.ContentType)
+		assert.Equal(t, int64(len(hdImage)), hd.Size)
+		assert.Equal(t, 8, hd.Width)
+		assert.Equal(t, 6, hd.Height)
+
+		sum := sha256.Sum256(hdImage)
+		assert.Equal(t, hex.EncodeToString(sum[:]), hd.SHA256)
+
+		object, _, err := imageStorage.Get(context.Background(), hd.StorageKey)
+		assert.NoError(t, err)
+		defer object.Close()
+
+		content, err := io.ReadAll(object)
+		assert.NoError(t, err)
+		assert.Equal(t, hdImage, content)
+	})
+
+	t.Run("HD failure keeps standard image", func(t *testing.T) {
+		err := apodService.SaveAPODData(context.Background(), models.APODResponse{
+			Date:  "2023-09-22",
+			URL:   srv.URL + "/standard.jpg",
+			HDURL: srv.URL + "/missing_hd.jpg",
+		})
+		assert.NoError(t, err)
+
+		saved := storedImage(t, repo, "2023-09-22")
+		assert.Len(t, saved.Assets, 1)
+		assert.Equal(t, domain.AssetVariantStandard, saved.Assets[0].Variant)
+	})
+}
+
+func TestGetImageContent(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+	repo := memory.NewMemoryRepository()
+	imageStorage := local.NewLocalStorage(t.TempDir())
+	apodService := NewApodImagesService(logger, repo, imageStorage, config.WorkerConfig{})
+
+	_, err := imageStorage.Put(ctx, "apod/2023-09-18.jpg", strings.NewReader("standard"), "image/jpeg")
+	assert.NoError(t, err)
+
+	repo.Save(context.Background(), domain.ApodImageMetaData{
+		Date: "2023-09-18",
+		Assets: []domain.ApodAsset{
+			{Variant: domain.AssetVariantStandard, StorageKey: "apod/2023-09-18.jpg"},
+			{Variant: domain.AssetVariantHD, StorageKey: "apod/2023-09-18_hd.jpg"},
+		},
+	}, domain.ConflictPolicySkip)
+
+	t.Run("returns stored image", func(t *testing.T) {
+		content, info, err := apodService.GetImageContent(ctx, "2023-09-18", domain.AssetVariantStandard)
+		assert.NoError(t, err)
+		defer content.Close()
+
+		body, err := io.ReadAll(content)
+		assert.NoError(t, err)
+		assert.Equal(t, "standard", string(body))
+		assert.Equal(t, "image/jpeg", info.ContentType)
+	})
+
+	t.Run("thumbnail falls back to standard image", func(t *testing.T) {
+		content, _, err := apodService.GetImageContent(ctx, "2023-09-18", domain.AssetVariantThumbnail)
+		assert.NoError(t, err)
+		content.Close()
+	})
+
+	t.Run("asset missing from storage", func(t *testing.T) {
+		_, _, err := apodService.GetImageContent(ctx, "2023-09-18", domain.AssetVariantHD)
+		assert.Equal(t, ErrAssetNotFound, err)
+	})
+
+	t.Run("image not found", func(t *testing.T) {
+		_, _, err := apodService.GetImageContent(ctx, "2023-09-19", domain.AssetVariantStandard)
+		assert.Equal(t, ErrImageNotFound, err)
+	})
+}
+
 func TestGetAllImages(t *testing.T) {
 	logger := zap.NewNop()
-	repo := NewInMemoryApodImagesRepo()
-	apodService := NewApodImagesService(logger, repo)
+	newService := func(t *testing.T, images ...domain.ApodImageMetaData) *ApodImagesService {
+		repo := memory.NewMemoryRepository()
+		for _, image := range images {
+			require.NoError(t, repo.Save(context.Background(), image, domain.ConflictPolicySkip))
+		}
+		return NewApodImagesService(logger, repo, local.NewLocalStorage(t.TempDir()), config.WorkerConfig{})
+	}
 
 	image := domain.ApodImageMetaData{Date: "2023-09-18", Title: "Test"}
-	repo.Save(image)
+	apodService := newService(t, image)
 
 	t.Run("successfully retrieves all APOD images", func(t *testing.T) {
-		images, err := apodService.GetAllImages(context.Background())
+		page, err := apodService.GetAllImages(context.Background(), domain.ApodImagesFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(page.Images))
+		assert.Equal(t, "2023-09-18", page.Images[0].Date)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("filters by media type", func(t *testing.T) {
+		video := domain.ApodImageMetaData{Date: "2023-09-19", Title: "Video", MediaType: domain.MediaTypeVideo}
+		apodService := newService(t, image, video)
+
+		page, err := apodService.GetAllImages(context.Background(), domain.ApodImagesFilter{MediaType: domain.MediaTypeVideo})
 		assert.NoError(t, err)
-		assert.Equal(t, 1, len(images))
-		assert.Equal(t, "2023-09-18", images[0].Date)
+		assert.Equal(t, []string{video.Date}, imageDates(page.Images))
+	})
+
+	t.Run("paginates with cursor", func(t *testing.T) {
+		apodService := newService(t, image, domain.ApodImageMetaData{Date: "2023-09-15"}, domain.ApodImageMetaData{Date: "2023-09-16"}, domain.ApodImageMetaData{Date: "2023-09-17"})
+
+		page, err := apodService.GetAllImages(context.Background(), domain.ApodImagesFilter{Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"2023-09-18", "2023-09-17"}, imageDates(page.Images))
+		assert.Equal(t, "2023-09-17", page.NextCursor)
+
+		page, err = apodService.GetAllImages(context.Background(), domain.ApodImagesFilter{Limit: 2, Cursor: page.NextCursor})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"2023-09-16", "2023-09-15"}, imageDates(page.Images))
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("filters by date range in ascending order", func(t *testing.T) {
+		apodService := newService(t, image, domain.ApodImageMetaData{Date: "2023-09-15"}, domain.ApodImageMetaData{Date: "2023-09-16"}, domain.ApodImageMetaData{Date: "2023-09-17"})
+
+		page, err := apodService.GetAllImages(context.Background(), domain.ApodImagesFilter{From: "2023-09-16", To: "2023-09-17", Order: domain.SortOrderAsc})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"2023-09-16", "2023-09-17"}, imageDates(page.Images))
+	})
+
+	t.Run("invalid pagination parameters", func(t *testing.T) {
+		_, err := apodService.GetAllImages(context.Background(), domain.ApodImagesFilter{Limit: MaxPageLimit + 1})
+		assert.Equal(t, ErrInvalidLimit, err)
+
+		_, err = apodService.GetAllImages(context.Background(), domain.ApodImagesFilter{Order: "sideways"})
+		assert.Equal(t, ErrInvalidOrder, err)
+
+		_, err = apodService.GetAllImages(context.Background(), domain.ApodImagesFilter{Cursor: "abc"})
+		assert.Equal(t, ErrInvalidCursor, err)
+
+		_, err = apodService.GetAllImages(context.Background(), domain.ApodImagesFilter{From: "yesterday"})
+		assert.Equal(t, ErrInvalidDate, err)
+	})
+
+	t.Run("invalid media type", func(t *testing.T) {
+		_, err := apodService.GetAllImages(context.Background(), domain.ApodImagesFilter{MediaType: "audio"})
+		assert.Equal(t, ErrInvalidMediaType, err)
 	})
 
 	t.Run("no images found", func(t *testing.T) {
-		repo.images = make(map[string]domain.ApodImageMetaData) // Clear the repo
-		_, err := apodService.GetAllImages(context.Background())
+		_, err := newService(t).GetAllImages(context.Background(), domain.ApodImagesFilter{})
 		assert.Error(t, err)
 		assert.Equal(t, ErrImagesNotFound, err)
 	})
 }
 
-type InMemoryApodImagesRepo struct {
-	images map[string]domain.ApodImageMetaData
-}
+func TestSearch(t *testing.T) {
+	logger := zap.NewNop()
+	repo := memory.NewMemoryRepository()
+	apodService := NewApodImagesService(logger, repo, local.NewLocalStorage(t.TempDir()), config.WorkerConfig{})
 
-func NewInMemoryApodImagesRepo() *InMemoryApodImagesRepo {
-	return &InMemoryApodImagesRepo{
-		images: make(map[string]domain.ApodImageMetaData),
-	}
-}
+	repo.Save(context.Background(), domain.ApodImageMetaData{Date: "2023-09-16", Title: "Orion Nebula", Explanation: "A nebula in Orion. The nebula is bright."}, domain.ConflictPolicySkip)
+	repo.Save(context.Background(), domain.ApodImageMetaData{Date: "2023-09-17", Title: "Crab Nebula", Explanation: "A supernova remnant."}, domain.ConflictPolicySkip)
+	repo.Save(context.Background(), domain.ApodImageMetaData{Date: "2023-09-18", Title: "Moon", Explanation: "The Moon over a lake."}, domain.ConflictPolicySkip)
 
-func (repo *InMemoryApodImagesRepo) GetAllImages(ctx context.Context) ([]domain.ApodImageMetaData, error) {
-	if len(repo.images) == 0 {
-		return nil, errors.New("images not found")
-	}
-	var images []domain.ApodImageMetaData
-	for _, img := range repo.images {
-		images = append(images, img)
-	}
-	return images, nil
+	t.Run("ranks and paginates results", func(t *testing.T) {
+		page, err := apodService.Search(context.Background(), domain.ApodSearchQuery{Query: "nebula", Limit: 1})
+		assert.NoError(t, err)
+		assert.Len(t, page.Results, 1)
+		assert.Equal(t, "2023-09-16", page.Results[0].Date)
+		assert.Equal(t, "1", page.NextCursor)
+
+		page, err = apodService.Search(context.Background(), domain.ApodSearchQuery{Query: "nebula", Limit: 1, Cursor: page.NextCursor})
+		assert.NoError(t, err)
+		assert.Len(t, page.Results, 1)
+		assert.Equal(t, "2023-09-17", page.Results[0].Date)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("no matches", func(t *testing.T) {
+		page, err := apodService.Search(context.Background(), domain.ApodSearchQuery{Query: "galaxy"})
+		assert.NoError(t, err)
+		assert.Empty(t, page.Results)
+	})
+
+	t.Run("empty query", func(t *testing.T) {
+		_, err := apodService.Search(context.Background(), domain.ApodSearchQuery{Query: "  "})
+		assert.Equal(t, ErrEmptySearchQuery, err)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, err := apodService.Search(context.Background(), domain.ApodSearchQuery{Query: "nebula", Cursor: "-1"})
+		assert.Equal(t, ErrInvalidCursor, err)
+	})
 }
 
-func (repo *InMemoryApodImagesRepo) GetImageByDate(ctx context.Context, date string) (*domain.ApodImageMetaData, error) {
-	img, exists := repo.images[date]
-	if !exists {
-		return nil, errors.New("image not found")
+func imageDates(images []domain.ApodImageMetaData) []string {
+	dates := make([]string, 0, len(images))
+	for _, image := range images {
+		dates = append(dates, image.Date)
 	}
-	return &img, nil
+	return dates
 }
 
-func (repo *InMemoryApodImagesRepo) ExistsByDate(date string) (bool, error) {
-	_, exists := repo.images[date]
-	return exists, nil
-}
+// storedImage reads what the service saved for date.
+func storedImage(t *testing.T, repo ApodImagesRepo, date string) domain.ApodImageMetaData {
+	t.Helper()
 
-func (repo *InMemoryApodImagesRepo) Save(metadata domain.ApodImageMetaData) error {
-	repo.images[metadata.Date] = metadata
-	return nil
+	image, err := repo.GetImageByDate(context.Background(), date)
+	require.NoError(t, err)
+	return *image
 }
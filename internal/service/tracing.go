@@ -0,0 +1,27 @@
+package service
+
+import (
+	"errors"
+	"nasa-apod-app/internal/domain"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer returns the service tracer of the current global provider. Storing it
+// in a package variable would pin the provider installed first and miss the
+// recording providers tests install per case.
+func tracer() trace.Tracer {
+	return otel.Tracer("nasa-apod-app/internal/service")
+}
+
+// endSpan ends span and marks it failed with err. A date that is already
+// stored is an expected outcome of a fetch, not a failure.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, domain.ErrAlreadyExists) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/models"
+	"nasa-apod-app/internal/repository/memory"
+	"nasa-apod-app/internal/storage/local"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+func TestFetchTrace(t *testing.T) {
+	exporter := recordSpans(t)
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/apod", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.APODResponse{Date: "2024-01-01", MediaType: "image", URL: srv.URL + "/image.png"})
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(encodeTestImage(t, "png", 2, 2))
+	})
+
+	logger := zap.NewNop()
+	worker := &APODWorker{
+		ApodService: NewApodImagesService(logger, memory.NewMemoryRepository(), local.NewLocalStorage(t.TempDir()), config.WorkerConfig{}),
+		APIKey:      "secret-key",
+		ApodURL:     srv.URL + "/apod",
+		Logger:      logger,
+	}
+
+	err := worker.fetchAPOD(context.Background(), "", "manual")
+	assert.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		byName[span.Name] = span
+		assert.Equal(t, spans[0].SpanContext.TraceID(), span.SpanContext.TraceID(), "all spans belong to one trace")
+
+		for _, attr := range span.Attributes {
+			assert.NotContains(t, attr.Value.Emit(), "secret-key")
+		}
+	}
+
+	for _, name := range []string{"APODWorker.fetchAPOD", "APODWorker.requestAPOD", "ApodImagesService.SaveAPODData", "ApodImagesService.downloadImage"} {
+		require.Contains(t, byName, name)
+	}
+
+	fetch := byName["APODWorker.fetchAPOD"]
+	assert.False(t, fetch.Parent.IsValid())
+	assert.Equal(t, fetch.SpanContext.SpanID(), byName["APODWorker.requestAPOD"].Parent.SpanID())
+	assert.Equal(t, fetch.SpanContext.SpanID(), byName["ApodImagesService.SaveAPODData"].Parent.SpanID())
+	assert.Equal(t, byName["ApodImagesService.SaveAPODData"].SpanContext.SpanID(), byName["ApodImagesService.downloadImage"].Parent.SpanID())
+
+	var requests []string
+	for _, span := range spans {
+		if span.Name == "HTTP GET" {
+			for _, attr := range span.Attributes {
+				if attr.Key == "url.path" {
+					requests = append(requests, attr.Value.AsString())
+				}
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{"/apod", "/image.png"}, requests)
+}
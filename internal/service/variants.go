@@ -0,0 +1,202 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"nasa-apod-app/internal/domain"
+	"strconv"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"golang.org/x/image/draw"
+)
+
+const variantJPEGQuality = 85
+
+var variantContentTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"webp": "image/webp",
+}
+
+// imageVariant is a resized copy generated from a downloaded image, e.g.
+// "320:webp" becomes the variant w320-webp, at most 320 pixels wide.
+type imageVariant struct {
+	name   string
+	width  int
+	format string
+}
+
+func parseImageVariants(specs []string, logger *zap.Logger) []imageVariant {
+	var variants []imageVariant
+	for _, spec := range specs {
+		widthValue, format, _ := strings.Cut(strings.TrimSpace(spec), ":")
+		if format == "" {
+			format = "jpeg"
+		}
+
+		width, err := strconv.Atoi(widthValue)
+		if _, known := variantContentTypes[format]; err != nil || width <= 0 || !known {
+			logger.Warn("Ignoring invalid image variant, use WIDTH:FORMAT with jpeg, png or webp", zap.String("variant", spec))
+			continue
+		}
+
+		variants = append(variants, imageVariant{
+			name:   fmt.Sprintf("w%d-%s", width, format),
+			width:  width,
+			format: format,
+		})
+	}
+	return variants
+}
+
+func (s *ApodImagesService) findVariant(name string) (imageVariant, bool) {
+	for _, variant := range s.variants {
+		if variant.name == name {
+			return variant, true
+		}
+	}
+	return imageVariant{}, false
+}
+
+// variantSource picks the largest downloaded image to resize from.
+func variantSource(assets []domain.ApodAsset) (domain.ApodAsset, bool) {
+	for _, variant := range []string{domain.AssetVariantHD, domain.AssetVariantStandard, domain.AssetVariantThumbnail} {
+		if asset, ok := findAsset(assets, variant); ok {
+			return asset, true
+		}
+	}
+	return domain.ApodAsset{}, false
+}
+
+// generateVariants creates every configured variant from the downloaded assets.
+// Failures are only logged, the original image is still worth keeping.
+func (s *ApodImagesService) generateVariants(ctx context.Context, date string, assets []domain.ApodAsset) []domain.ApodAsset {
+	source, ok := variantSource(assets)
+	if !ok || len(s.variants) == 0 {
+		return nil
+	}
+
+	var generated []domain.ApodAsset
+	for _, variant := range s.variants {
+		asset, err := s.generateVariant(ctx, date, source, variant)
+		if err != nil {
+			s.logger.Warn("Failed to generate image variant", zap.String("date", date), zap.String("variant", variant.name), zap.Error(err))
+			continue
+		}
+		generated = append(generated, asset)
+	}
+	return generated
+}
+
+// generateVariant decodes source, scales it down to the variant width and
+// stores the result. Decoding needs the whole image in memory, so only as
+// many variants as there are processing slots are generated at once.
+func (s *ApodImagesService) generateVariant(ctx context.Context, date string, source domain.ApodAsset, variant imageVariant) (asset domain.ApodAsset, err error) {
+	ctx, span := tracer().Start(ctx, "ApodImagesService.generateVariant", trace.WithAttributes(attribute.String("apod.date", date), attribute.String("apod.variant", variant.name)))
+	defer func() { endSpan(span, err) }()
+
+	select {
+	case s.processing <- struct{}{}:
+		defer func() { <-s.processing }()
+	case <-ctx.Done():
+		return domain.ApodAsset{}, ctx.Err()
+	}
+
+	object, _, err := s.storage.Get(ctx, source.StorageKey)
+	if err != nil {
+		return domain.ApodAsset{}, fmt.Errorf("failed to open source image: %w", err)
+	}
+	defer object.Close()
+
+	original, _, err := image.Decode(object)
+	if err != nil {
+		return domain.ApodAsset{}, fmt.Errorf("failed to decode source image: %w", err)
+	}
+
+	resized := resizeToWidth(original, variant.width)
+
+	var buf bytes.Buffer
+	switch variant.format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: variantJPEGQuality})
+	case "png":
+		err = png.Encode(&buf, resized)
+	case "webp":
+		err = nativewebp.Encode(&buf, resized, nil)
+	}
+	if err != nil {
+		return domain.ApodAsset{}, fmt.Errorf("failed to encode %s variant: %w", variant.format, err)
+	}
+
+	contentType := variantContentTypes[variant.format]
+	key := storageKey(date, variant.name, contentType)
+	sum := sha256.Sum256(buf.Bytes())
+
+	info, err := s.storage.Put(ctx, key, &buf, contentType)
+	if err != nil {
+		return domain.ApodAsset{}, fmt.Errorf("failed to store variant: %w", err)
+	}
+
+	bounds := resized.Bounds()
+	return domain.ApodAsset{
+		Variant:     variant.name,
+		SourceURL:   source.SourceURL,
+		StorageKey:  key,
+		SHA256:      hex.EncodeToString(sum[:]),
+		Size:        info.Size,
+		ContentType: contentType,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}, nil
+}
+
+// regenerateVariant generates a configured variant that is missing from the
+// database or from storage and records it for image.
+func (s *ApodImagesService) regenerateVariant(ctx context.Context, image *domain.ApodImageMetaData, variant imageVariant) (domain.ApodAsset, error) {
+	unlock := s.saving.lock(image.Date)
+	defer unlock()
+
+	source, ok := variantSource(image.Assets)
+	if !ok {
+		return domain.ApodAsset{}, ErrAssetNotFound
+	}
+
+	s.logger.Info("Regenerating missing image variant", zap.String("date", image.Date), zap.String("variant", variant.name))
+
+	asset, err := s.generateVariant(ctx, image.Date, source, variant)
+	if err != nil {
+		return domain.ApodAsset{}, err
+	}
+
+	// Refreshing with the stored metadata leaves it as is and upserts the asset row.
+	metadata := *image
+	metadata.Assets = []domain.ApodAsset{asset}
+	if err := s.repository.Save(ctx, metadata, domain.ConflictPolicyRefresh); err != nil {
+		return domain.ApodAsset{}, fmt.Errorf("failed to record regenerated variant: %w", err)
+	}
+	return asset, nil
+}
+
+// resizeToWidth scales img down to width, keeping its aspect ratio. Images
+// that are already narrow enough are never scaled up.
+func resizeToWidth(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() <= width {
+		return img
+	}
+
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, bounds, draw.Src, nil)
+	return resized
+}
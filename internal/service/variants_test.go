@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"image"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/models"
+	"nasa-apod-app/internal/repository/memory"
+	"nasa-apod-app/internal/storage/local"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestParseImageVariants(t *testing.T) {
+	variants := parseImageVariants([]string{"320:webp", " 1024 ", "0:jpeg", "abc:png", "640:gif"}, zap.NewNop())
+
+	assert.Equal(t, []imageVariant{
+		{name: "w320-webp", width: 320, format: "webp"},
+		{name: "w1024-jpeg", width: 1024, format: "jpeg"},
+	}, variants)
+}
+
+func TestImageVariants(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(encodeTestImage(t, "png", 8, 6))
+	}))
+	defer srv.Close()
+
+	repo := memory.NewMemoryRepository()
+	imageStorage := local.NewLocalStorage(t.TempDir())
+	workerConfig := config.WorkerConfig{ImageVariants: []string{"4:jpeg", "2:webp", "100:png"}, ImageProcessingWorkers: 1}
+	apodService := NewApodImagesService(zap.NewNop(), repo, imageStorage, workerConfig)
+
+	err := apodService.SaveAPODData(ctx, models.APODResponse{Date: "2024-01-01", MediaType: domain.MediaTypeImage, URL: srv.URL})
+	assert.NoError(t, err)
+
+	decodeVariant := func(t *testing.T, variant string) (string, image.Config) {
+		t.Helper()
+
+		content, info, err := apodService.GetImageContent(ctx, "2024-01-01", variant)
+		assert.NoError(t, err)
+		defer content.Close()
+
+		config, format, err := image.DecodeConfig(content)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, info.Key)
+		return format, config
+	}
+
+	t.Run("generates configured variants after download", func(t *testing.T) {
+		saved := storedImage(t, repo, "2024-01-01")
+		assert.Len(t, saved.Assets, 4)
+
+		expected := map[string][3]interface{}{
+			"w4-jpeg":  {"image/jpeg", 4, 3},
+			"w2-webp":  {"image/webp", 2, 1},
+			"w100-png": {"image/png", 8, 6},
+			"standard": {"image/png", 8, 6},
+		}
+		for _, asset := range saved.Assets {
+			want, ok := expected[asset.Variant]
+			assert.True(t, ok, asset.Variant)
+			assert.Equal(t, want, [3]interface{}{asset.ContentType, asset.Width, asset.Height}, asset.Variant)
+			assert.NotEmpty(t, asset.SHA256)
+		}
+
+		format, config := decodeVariant(t, "w2-webp")
+		assert.Equal(t, "webp", format)
+		assert.Equal(t, 2, config.Width)
+	})
+
+	t.Run("regenerates a variant whose file is gone", func(t *testing.T) {
+		asset, _ := findAsset(storedImage(t, repo, "2024-01-01").Assets, "w4-jpeg")
+		assert.NoError(t, imageStorage.Delete(ctx, asset.StorageKey))
+
+		format, config := decodeVariant(t, "w4-jpeg")
+		assert.Equal(t, "jpeg", format)
+		assert.Equal(t, 4, config.Width)
+	})
+
+	t.Run("generates a newly configured variant on demand", func(t *testing.T) {
+		workerConfig.ImageVariants = append(workerConfig.ImageVariants, "6:jpeg")
+		apodService = NewApodImagesService(zap.NewNop(), repo, imageStorage, workerConfig)
+
+		_, config := decodeVariant(t, "w6-jpeg")
+		assert.Equal(t, 6, config.Width)
+
+		_, ok := findAsset(storedImage(t, repo, "2024-01-01").Assets, "w6-jpeg")
+		assert.True(t, ok)
+	})
+
+	t.Run("unknown variants are not found", func(t *testing.T) {
+		_, _, err := apodService.GetImageContent(ctx, "2024-01-01", "w9-jpeg")
+		assert.Equal(t, ErrAssetNotFound, err)
+	})
+}
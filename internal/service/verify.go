@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/storage"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// Verify re-hashes every stored asset and reports files that are missing or
+// no longer match the size and checksum recorded when they were downloaded.
+func (s *ApodImagesService) Verify(ctx context.Context) (*domain.VerifyReport, error) {
+	report := &domain.VerifyReport{Issues: []domain.VerifyIssue{}}
+
+	filter := domain.ApodImagesFilter{Order: domain.SortOrderAsc, Limit: MaxPageLimit}
+	for {
+		page, err := s.GetAllImages(ctx, filter)
+		if errors.Is(err, ErrImagesNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list APOD images: %w", err)
+		}
+
+		for _, image := range page.Images {
+			for _, asset := range image.Assets {
+				issue, verified, err := s.verifyAsset(ctx, asset)
+				if err != nil {
+					return nil, err
+				}
+
+				report.Checked++
+				if !verified {
+					report.Unverified++
+				}
+				if issue != nil {
+					issue.Date = image.Date
+					report.Issues = append(report.Issues, *issue)
+				}
+			}
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+
+	s.logger.Info("Verified stored assets", zap.Int("checked", report.Checked), zap.Int("unverified", report.Unverified), zap.Int("issues", len(report.Issues)))
+	return report, nil
+}
+
+func (s *ApodImagesService) verifyAsset(ctx context.Context, asset domain.ApodAsset) (*domain.VerifyIssue, bool, error) {
+	issue := &domain.VerifyIssue{Variant: asset.Variant, StorageKey: asset.StorageKey}
+
+	object, info, err := s.storage.Get(ctx, asset.StorageKey)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		issue.Problem = domain.VerifyProblemMissing
+		return issue, asset.SHA256 != "", nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open %s: %w", asset.StorageKey, err)
+	}
+	defer object.Close()
+
+	if asset.SHA256 == "" {
+		return nil, false, nil
+	}
+
+	if info.Size != asset.Size {
+		issue.Problem = domain.VerifyProblemSizeMismatch
+		issue.Expected = strconv.FormatInt(asset.Size, 10)
+		issue.Actual = strconv.FormatInt(info.Size, 10)
+		return issue, true, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, object); err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", asset.StorageKey, err)
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != asset.SHA256 {
+		issue.Problem = domain.VerifyProblemChecksumMismatch
+		issue.Expected = asset.SHA256
+		issue.Actual = sum
+		return issue, true, nil
+	}
+	return nil, true, nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/models"
+	"nasa-apod-app/internal/repository/memory"
+	"nasa-apod-app/internal/storage/local"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(encodeTestImage(t, "png", 2, 2))
+	}))
+	defer srv.Close()
+
+	repo := memory.NewMemoryRepository()
+	imageStorage := local.NewLocalStorage(t.TempDir())
+	apodService := NewApodImagesService(zap.NewNop(), repo, imageStorage, config.WorkerConfig{})
+
+	t.Run("empty archive", func(t *testing.T) {
+		report, err := apodService.Verify(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, report.Checked)
+		assert.Empty(t, report.Issues)
+	})
+
+	for _, date := range []string{"2024-01-01", "2024-01-02", "2024-01-03", "2024-01-04"} {
+		err := apodService.SaveAPODData(ctx, models.APODResponse{Date: date, MediaType: domain.MediaTypeImage, URL: srv.URL})
+		assert.NoError(t, err)
+	}
+
+	t.Run("intact files", func(t *testing.T) {
+		report, err := apodService.Verify(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, report.Checked)
+		assert.Empty(t, report.Issues)
+	})
+
+	t.Run("flags missing and modified files", func(t *testing.T) {
+		assert.NoError(t, imageStorage.Delete(ctx, "apod/2024-01-01.png"))
+
+		_, err := imageStorage.Put(ctx, "apod/2024-01-02.png", strings.NewReader("truncated"), "image/png")
+		assert.NoError(t, err)
+
+		modified := encodeTestImage(t, "png", 2, 2)
+		modified[len(modified)-5] ^= 0xff
+		_, err = imageStorage.Put(ctx, "apod/2024-01-03.png", strings.NewReader(string(modified)), "image/png")
+		assert.NoError(t, err)
+
+		report, err := apodService.Verify(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, report.Checked)
+		assert.Len(t, report.Issues, 3)
+
+		problems := make(map[string]string)
+		for _, issue := range report.Issues {
+			problems[issue.Date] = issue.Problem
+		}
+		assert.Equal(t, map[string]string{
+			"2024-01-01": domain.VerifyProblemMissing,
+			"2024-01-02": domain.VerifyProblemSizeMismatch,
+			"2024-01-03": domain.VerifyProblemChecksumMismatch,
+		}, problems)
+	})
+
+	t.Run("assets without checksum are only checked for presence", func(t *testing.T) {
+		repo.Save(context.Background(), domain.ApodImageMetaData{
+			Date:   "2024-01-05",
+			Assets: []domain.ApodAsset{{Variant: domain.AssetVariantStandard, StorageKey: "apod/2024-01-04.png"}},
+		}, domain.ConflictPolicySkip)
+
+		report, err := apodService.Verify(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, report.Checked)
+		assert.Equal(t, 1, report.Unverified)
+	})
+}
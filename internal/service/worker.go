@@ -1,92 +1,423 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/domain"
 	"nasa-apod-app/internal/models"
 	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+const apodDateLayout = "2006-01-02"
+
+var ErrInvalidBackfillRange = fmt.Errorf("backfill end date is before start date")
+
 type APODWorker struct {
-	ApodService    *ApodImagesService
-	APIKey         string
-	ApodURL        string
-	RunImmediately bool
-	RunTime        time.Time
-	Logger         *zap.Logger
+	ApodService       *ApodImagesService
+	Runs              FetchRunsRepo
+	APIKey            string
+	ApodURL           string
+	RunImmediately    bool
+	RunTime           time.Time
+	Schedule          *Schedule
+	Clock             Clock
+	BackfillFrom      time.Time
+	BackfillTo        time.Time
+	BackfillBatchDays int
+	MissedFetchRetry  time.Duration
+	Logger            *zap.Logger
+
+	wg   sync.WaitGroup
+	jobs jobQueue
+
+	// lastFetch holds the unix nano time of the last fetch that reached NASA
+	// and left the day stored, or of Start until there is one.
+	lastFetch atomic.Int64
 }
 
-func NewAPODWorker(apodService *ApodImagesService, apiKey string, workerConfig config.WorkerConfig, logger *zap.Logger) *APODWorker {
-	return &APODWorker{
-		ApodService:    apodService,
-		APIKey:         apiKey,
-		ApodURL:        workerConfig.ApiURL,
-		RunImmediately: workerConfig.RunFetchingOnStart,
-		RunTime:        workerConfig.RunTime,
-		Logger:         logger,
+func NewAPODWorker(apodService *ApodImagesService, runs FetchRunsRepo, apiKey string, workerConfig config.WorkerConfig, logger *zap.Logger) (*APODWorker, error) {
+	schedule := DailySchedule(workerConfig.RunTime, workerConfig.Timezone)
+	if len(workerConfig.Schedules) > 0 {
+		var err error
+		schedule, err = NewSchedule(workerConfig.Schedules, workerConfig.Timezone)
+		if err != nil {
+			return nil, err
+		}
 	}
+
+	return &APODWorker{
+		ApodService:       apodService,
+		Runs:              runs,
+		APIKey:            apiKey,
+		ApodURL:           workerConfig.ApiURL,
+		RunImmediately:    workerConfig.RunFetchingOnStart,
+		RunTime:           workerConfig.RunTime,
+		Schedule:          schedule,
+		Clock:             realClock{},
+		BackfillFrom:      workerConfig.BackfillFrom,
+		BackfillTo:        workerConfig.BackfillTo,
+		BackfillBatchDays: workerConfig.BackfillBatchDays,
+		MissedFetchRetry:  workerConfig.MissedFetchRetry,
+		Logger:            logger,
+	}, nil
 }
 
-func (w *APODWorker) Start() {
+// Start runs the worker in the background until ctx is cancelled. Cancelling
+// ctx also aborts fetches in progress, use Wait to block until they are gone.
+func (w *APODWorker) Start(ctx context.Context) {
+	w.lastFetch.CompareAndSwap(0, w.clock().Now().UnixNano())
+
 	if w.RunImmediately {
-		go w.fetchAPOD()
+		w.goTracked(func() { w.fetchAPODWithinDay(ctx) })
 	}
 
-	go func() {
+	if !w.BackfillFrom.IsZero() {
+		w.goTracked(func() {
+			if err := w.Backfill(ctx, w.BackfillFrom, w.BackfillTo); err != nil {
+				w.Logger.Error("APOD backfill finished with errors", zap.Error(err))
+			}
+		})
+	}
+
+	w.goTracked(func() { w.runJobs(ctx) })
+
+	w.goTracked(func() {
+		clock := w.clock()
 		for {
-			nextRun := w.calculateNextRunTime(time.Now())
+			now := clock.Now()
+			nextRun := w.calculateNextRunTime(now)
 			w.Logger.Info("Next APOD fetch scheduled at", zap.String("time", nextRun.Format(time.RFC3339)))
 
-			time.Sleep(time.Until(nextRun))
+			select {
+			case <-ctx.Done():
+				w.Logger.Info("APOD worker scheduler stopped")
+				return
+			case <-clock.After(nextRun.Sub(now)):
+			}
 
-			go w.fetchAPOD()
+			w.goTracked(func() { w.fetchAPODWithinDay(ctx) })
 		}
+	})
+}
+
+// Wait blocks until every goroutine started by the worker has returned or ctx
+// expires, whichever comes first.
+func (w *APODWorker) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("APOD worker did not stop in time: %w", ctx.Err())
+	}
+}
+
+// LastSuccessfulFetch returns when a fetch last succeeded or found its day
+// already stored. Until the first one it returns the time the worker started,
+// so a fresh instance is not reported stale right away.
+func (w *APODWorker) LastSuccessfulFetch() time.Time {
+	last := w.lastFetch.Load()
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
+
+func (w *APODWorker) goTracked(fn func()) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		fn()
 	}()
 }
 
 func (w *APODWorker) calculateNextRunTime(currentTime time.Time) time.Time {
-	nextRun := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), w.RunTime.Hour(), w.RunTime.Minute(), 0, 0, currentTime.Location())
+	schedule := w.Schedule
+	if schedule == nil {
+		schedule = DailySchedule(w.RunTime, currentTime.Location())
+	}
+
+	return schedule.Next(currentTime)
+}
 
-	if currentTime.After(nextRun) {
-		nextRun = nextRun.Add(24 * time.Hour)
+// today is the current date in the time zone of the schedule, at midnight UTC
+// like the dates parsed from requests.
+func (w *APODWorker) today() time.Time {
+	now := w.clock().Now()
+	if w.Schedule != nil {
+		now = now.In(w.Schedule.location)
 	}
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
 
-	return nextRun
+func (w *APODWorker) clock() Clock {
+	if w.Clock == nil {
+		return realClock{}
+	}
+	return w.Clock
 }
 
-func (w *APODWorker) fetchAPOD() {
-	w.Logger.Info("Fetching APOD data from NASA API with URL: " + w.ApodURL)
+// fetchAPODWithinDay keeps retrying a failed fetch every MissedFetchRetry
+// until the next scheduled run, so a bad hour does not cost a whole day.
+func (w *APODWorker) fetchAPODWithinDay(ctx context.Context) {
+	clock := w.clock()
+	nextRun := w.calculateNextRunTime(clock.Now())
 
-	url := fmt.Sprintf("%s?api_key=%s", w.ApodURL, w.APIKey)
-	w.Logger.Info("URL: " + url)
+	for {
+		err := w.fetchAPOD(ctx, "", domain.FetchTriggerSchedule)
+		if err == nil || errors.Is(err, domain.ErrAlreadyExists) || ctx.Err() != nil {
+			return
+		}
 
-	resp, err := http.Get(url)
-	if err != nil {
-		w.Logger.Error("Failed to request APOD API", zap.Error(err))
-		return
+		retryAt := clock.Now().Add(w.MissedFetchRetry)
+		if w.MissedFetchRetry <= 0 || !retryAt.Before(nextRun) {
+			w.Logger.Error("Giving up on APOD fetch until the next scheduled run", zap.Error(err))
+			return
+		}
+
+		w.Logger.Warn("APOD fetch failed, retrying later", zap.String("retry_at", retryAt.Format(time.RFC3339)), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(w.MissedFetchRetry):
+		}
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		w.Logger.Error("APOD API returned non-200 status", zap.Int("status_code", resp.StatusCode))
-		return
+// fetchAPOD fetches and saves the APOD for date, or the latest one when date
+// is empty. Every call is recorded as a fetch run.
+func (w *APODWorker) fetchAPOD(ctx context.Context, date, trigger string) (err error) {
+	ctx, span := tracer().Start(ctx, "APODWorker.fetchAPOD", trace.WithAttributes(attribute.String("apod.trigger", trigger)))
+	ctx, stats := withFetchStats(ctx)
+	startedAt := w.clock().Now()
+	targetDate := date
+	defer func() {
+		w.recordFetchRun(ctx, trigger, targetDate, startedAt, stats, err)
+		span.SetAttributes(attribute.String("apod.date", targetDate))
+		endSpan(span, err)
+	}()
+
+	w.Logger.Info("Fetching APOD data from NASA API with URL: "+w.ApodURL, zap.String("date", date))
+
+	params := url.Values{}
+	if date != "" {
+		params.Set("date", date)
 	}
 
 	var apodData models.APODResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apodData); err != nil {
-		w.Logger.Error("Failed to decode APOD response", zap.Error(err))
-		return
+	if err := w.requestAPOD(ctx, params, &apodData); err != nil {
+		w.Logger.Error("Failed to fetch APOD data", zap.Error(err))
+		return err
 	}
+	targetDate = apodData.Date
 
-	err = w.ApodService.SaveAPODData(apodData)
+	err = w.ApodService.SaveAPODData(ctx, apodData)
 	if err != nil {
 		w.Logger.Error("Failed to save APOD data", zap.Error(err))
-		return
+		return err
 	}
 
 	w.Logger.Info("APOD data successfully fetched and saved.")
+	return nil
+}
+
+// Fetch fetches and saves the APOD for date, or the latest one when date is
+// empty, and returns domain.ErrAlreadyExists when it is stored already.
+func (w *APODWorker) Fetch(ctx context.Context, date string) error {
+	if date != "" {
+		if _, err := time.Parse(apodDateLayout, date); err != nil {
+			return ErrInvalidDate
+		}
+	}
+	return w.fetchAPOD(ctx, date, domain.FetchTriggerManual)
+}
+
+// Backfill saves every APOD between startDate and endDate (inclusive) that is
+// not stored yet. The range is processed in batches and already stored days are
+// skipped, so an interrupted backfill can simply be started again.
+func (w *APODWorker) Backfill(ctx context.Context, startDate, endDate time.Time) error {
+	return w.backfill(ctx, startDate, endDate, func(domain.JobProgress) {})
+}
+
+func (w *APODWorker) backfill(ctx context.Context, startDate, endDate time.Time, report func(domain.JobProgress)) (err error) {
+	ctx, span := tracer().Start(ctx, "APODWorker.backfill", trace.WithAttributes(attribute.String("apod.from", startDate.Format(apodDateLayout)), attribute.String("apod.to", endDate.Format(apodDateLayout))))
+	defer func() { endSpan(span, err) }()
+
+	if endDate.IsZero() {
+		endDate = w.today()
+	}
+
+	batches, err := splitDateRange(startDate, endDate, w.BackfillBatchDays)
+	if err != nil {
+		return err
+	}
+
+	w.Logger.Info("Starting APOD backfill",
+		zap.String("start_date", startDate.Format(apodDateLayout)),
+		zap.String("end_date", endDate.Format(apodDateLayout)),
+	)
+
+	progress := domain.JobProgress{Total: int(endDate.Sub(startDate).Hours()/24) + 1}
+	report(progress)
+
+	for _, batch := range batches {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("APOD backfill interrupted: %w", err)
+		}
+
+		missing, err := w.missingDates(ctx, batch[0], batch[1])
+		if err != nil {
+			return fmt.Errorf("failed to check stored APOD dates: %w", err)
+		}
+
+		batchDays := int(batch[1].Sub(batch[0]).Hours()/24) + 1
+		progress.Skipped += batchDays - len(missing)
+
+		if len(missing) == 0 {
+			progress.Done += batchDays
+			report(progress)
+			continue
+		}
+
+		rangeCtx, rangeStats := withFetchStats(ctx)
+		rangeStartedAt := w.clock().Now()
+
+		entries, err := w.fetchAPODRange(rangeCtx, batch[0], batch[1])
+		if err != nil {
+			for date := range missing {
+				w.recordFetchRun(ctx, domain.FetchTriggerBackfill, date, rangeStartedAt, rangeStats, err)
+			}
+			return fmt.Errorf("failed to fetch APOD range %s - %s: %w", batch[0].Format(apodDateLayout), batch[1].Format(apodDateLayout), err)
+		}
+
+		for _, entry := range entries {
+			if !missing[entry.Date] {
+				continue
+			}
+
+			entryCtx, entryStats := withFetchStats(ctx)
+			entryStats.setHTTPStatus(rangeStats.httpStatus)
+			entryStartedAt := w.clock().Now()
+
+			err := w.ApodService.SaveAPODData(entryCtx, entry)
+			w.recordFetchRun(ctx, domain.FetchTriggerBackfill, entry.Date, entryStartedAt, entryStats, err)
+			if err != nil {
+				if ctx.Err() != nil {
+					return fmt.Errorf("APOD backfill interrupted: %w", ctx.Err())
+				}
+
+				w.Logger.Error("Failed to save backfilled APOD data", zap.String("date", entry.Date), zap.Error(err))
+				progress.Failed++
+				report(progress)
+				continue
+			}
+			progress.Saved++
+			report(progress)
+		}
+
+		progress.Done += batchDays
+		report(progress)
+	}
+
+	w.Logger.Info("APOD backfill finished", zap.Int("saved", progress.Saved), zap.Int("failed", progress.Failed))
+
+	if progress.Failed > 0 {
+		return fmt.Errorf("failed to save %d APOD entries during backfill", progress.Failed)
+	}
+	return nil
+}
+
+func (w *APODWorker) missingDates(ctx context.Context, startDate, endDate time.Time) (map[string]bool, error) {
+	missing := make(map[string]bool)
+	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		date := day.Format(apodDateLayout)
+
+		exists, err := w.ApodService.repository.ExistsByDate(ctx, date)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			missing[date] = true
+		}
+	}
+	return missing, nil
+}
+
+func (w *APODWorker) fetchAPODRange(ctx context.Context, startDate, endDate time.Time) ([]models.APODResponse, error) {
+	params := url.Values{}
+	params.Set("start_date", startDate.Format(apodDateLayout))
+	params.Set("end_date", endDate.Format(apodDateLayout))
+
+	var entries []models.APODResponse
+	if err := w.requestAPOD(ctx, params, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (w *APODWorker) requestAPOD(ctx context.Context, params url.Values, target interface{}) (err error) {
+	ctx, span := tracer().Start(ctx, "APODWorker.requestAPOD")
+	defer func() { endSpan(span, err) }()
+
+	params.Set("api_key", w.APIKey)
+	params.Set("thumbs", "true")
+
+	requestURL := w.ApodURL + "?" + params.Encode()
+	w.Logger.Info("Requesting APOD", zap.String("url", config.RedactURL(requestURL)))
+
+	resp, err := w.ApodService.retry.Get(ctx, requestURL)
+	if err != nil {
+		return fmt.Errorf("failed to request APOD API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	fetchStatsFrom(ctx).setHTTPStatus(resp.StatusCode)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("APOD API returned non-200 status: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode APOD response: %w", err)
+	}
+	return nil
+}
+
+func splitDateRange(startDate, endDate time.Time, batchDays int) ([][2]time.Time, error) {
+	if endDate.Before(startDate) {
+		return nil, ErrInvalidBackfillRange
+	}
+
+	if batchDays <= 0 {
+		batchDays = 1
+	}
+
+	var batches [][2]time.Time
+	for batchStart := startDate; !batchStart.After(endDate); {
+		batchEnd := batchStart.AddDate(0, 0, batchDays-1)
+		if batchEnd.After(endDate) {
+			batchEnd = endDate
+		}
+
+		batches = append(batches, [2]time.Time{batchStart, batchEnd})
+		batchStart = batchEnd.AddDate(0, 0, 1)
+	}
+	return batches, nil
 }
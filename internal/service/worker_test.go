@@ -1,7 +1,18 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/domain"
+	"nasa-apod-app/internal/models"
+	"nasa-apod-app/internal/repository/memory"
+	"nasa-apod-app/internal/storage/local"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -44,3 +55,143 @@ func TestCalculateNextRunTime(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitDateRange(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("splits range into batches", func(t *testing.T) {
+		batches, err := splitDateRange(start, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), 2)
+		assert.NoError(t, err)
+		assert.Equal(t, [][2]time.Time{
+			{start, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+			{time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)},
+			{time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		}, batches)
+	})
+
+	t.Run("single day range", func(t *testing.T) {
+		batches, err := splitDateRange(start, start, 30)
+		assert.NoError(t, err)
+		assert.Equal(t, [][2]time.Time{{start, start}}, batches)
+	})
+
+	t.Run("end before start", func(t *testing.T) {
+		_, err := splitDateRange(start, start.AddDate(0, 0, -1), 30)
+		assert.Equal(t, ErrInvalidBackfillRange, err)
+	})
+}
+
+func TestBackfill(t *testing.T) {
+	var apiRequests int
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/apod", func(w http.ResponseWriter, r *http.Request) {
+		apiRequests++
+		assert.Equal(t, "2024-01-01", r.URL.Query().Get("start_date"))
+		assert.Equal(t, "2024-01-02", r.URL.Query().Get("end_date"))
+
+		json.NewEncoder(w).Encode([]models.APODResponse{
+			{Date: "2024-01-01", Title: "Stored", URL: srv.URL + "/image"},
+			{Date: "2024-01-02", Title: "Missing", URL: srv.URL + "/image"},
+		})
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image"))
+	})
+
+	logger := zap.NewNop()
+	repo := memory.NewMemoryRepository()
+	repo.Save(context.Background(), domain.ApodImageMetaData{Date: "2024-01-01", Title: "Stored"}, domain.ConflictPolicySkip)
+	repo.Save(context.Background(), domain.ApodImageMetaData{Date: "2024-01-03", Title: "Stored"}, domain.ConflictPolicySkip)
+
+	worker := &APODWorker{
+		ApodService:       NewApodImagesService(logger, repo, local.NewLocalStorage(t.TempDir()), config.WorkerConfig{}),
+		ApodURL:           srv.URL + "/apod",
+		BackfillBatchDays: 2,
+		Logger:            logger,
+	}
+
+	assert.True(t, worker.LastSuccessfulFetch().IsZero())
+
+	err := worker.Backfill(context.Background(), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, apiRequests)
+	assert.False(t, worker.LastSuccessfulFetch().IsZero())
+	assert.Equal(t, "Stored", storedImage(t, repo, "2024-01-01").Title)
+	assert.Equal(t, "Missing", storedImage(t, repo, "2024-01-02").Title)
+}
+
+func TestBackfillDefaultsToToday(t *testing.T) {
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Query().Get("start_date")+".."+r.URL.Query().Get("end_date"))
+		json.NewEncoder(w).Encode([]models.APODResponse{})
+	}))
+	defer srv.Close()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	logger := zap.NewNop()
+	worker := &APODWorker{
+		ApodService: NewApodImagesService(logger, memory.NewMemoryRepository(), local.NewLocalStorage(t.TempDir()), config.WorkerConfig{}),
+		ApodURL:     srv.URL + "/apod",
+		Schedule:    DailySchedule(time.Date(0, 1, 1, 6, 0, 0, 0, time.UTC), newYork),
+		// Already January 3rd in UTC, still January 2nd in New York.
+		Clock:             &fakeClock{now: time.Date(2024, 1, 3, 3, 0, 0, 0, time.UTC)},
+		BackfillBatchDays: 30,
+		Logger:            logger,
+	}
+
+	require.NoError(t, worker.Backfill(context.Background(), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}))
+	assert.Equal(t, []string{"2024-01-01..2024-01-02"}, requested)
+}
+
+func TestWorkerStopsOnCancel(t *testing.T) {
+	downloadStarted := make(chan struct{})
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/apod", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.APODResponse{Date: "2024-01-01", MediaType: "image", URL: srv.URL + "/image"})
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		close(downloadStarted)
+		<-r.Context().Done()
+	})
+
+	logger := zap.NewNop()
+	repo := memory.NewMemoryRepository()
+	imageStorage := local.NewLocalStorage(t.TempDir())
+
+	worker := &APODWorker{
+		ApodService:    NewApodImagesService(logger, repo, imageStorage, config.WorkerConfig{Retry: config.RetryConfig{MaxAttempts: 1}}),
+		ApodURL:        srv.URL + "/apod",
+		RunImmediately: true,
+		RunTime:        time.Now().Add(time.Hour),
+		Logger:         logger,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	worker.Start(ctx)
+
+	<-downloadStarted
+	cancel()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	assert.NoError(t, worker.Wait(waitCtx))
+
+	exists, err := repo.ExistsByDate(context.Background(), "2024-01-01")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	objects, err := imageStorage.List(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Empty(t, objects)
+}
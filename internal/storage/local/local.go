@@ -0,0 +1,183 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"nasa-apod-app/internal/storage"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tempFilePrefix marks files of writes in progress, they are never listed.
+const tempFilePrefix = ".tmp-"
+
+type LocalStorage struct {
+	root string
+}
+
+func NewLocalStorage(root string) *LocalStorage {
+	return &LocalStorage{
+		root: root,
+	}
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) (storage.ObjectInfo, error) {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return storage.ObjectInfo{}, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	// Write next to the target and rename, so readers never see a half written
+	// object and a failed write leaves the previous version in place.
+	file, err := os.CreateTemp(filepath.Dir(filePath), tempFilePrefix+filepath.Base(filePath)+"-*")
+	if err != nil {
+		return storage.ObjectInfo{}, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tempPath := file.Name()
+
+	_, err = io.Copy(file, r)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		// Temp files are created private, stored objects are not.
+		err = file.Chmod(0o644)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, filePath)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return storage.ObjectInfo{}, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return s.Stat(ctx, key)
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadSeekCloser, storage.ObjectInfo, error) {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, wrapNotFound(key, err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, storage.ObjectInfo{}, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return file, objectInfo(key, stat), nil
+}
+
+func (s *LocalStorage) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return storage.ObjectInfo{}, wrapNotFound(key, err)
+	}
+
+	return objectInfo(key, stat), nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	var objects []storage.ObjectInfo
+
+	err := filepath.WalkDir(s.root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), tempFilePrefix) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(s.root, filePath)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(relPath)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		stat, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, objectInfo(key, stat))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects, nil
+}
+
+func (s *LocalStorage) filePath(key string) (string, error) {
+	cleanKey := path.Clean("/" + key)
+	if cleanKey == "/" {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleanKey)), nil
+}
+
+func objectInfo(key string, stat fs.FileInfo) storage.ObjectInfo {
+	return storage.ObjectInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		ContentType:  mime.TypeByExtension(path.Ext(key)),
+		ETag:         fmt.Sprintf(`"%x-%x"`, stat.ModTime().UnixNano(), stat.Size()),
+		LastModified: stat.ModTime(),
+	}
+}
+
+func wrapNotFound(key string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", storage.ErrObjectNotFound, key)
+	}
+	return fmt.Errorf("failed to open file: %w", err)
+}
@@ -0,0 +1,89 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"io"
+	"nasa-apod-app/internal/storage"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocalStorage(t.TempDir())
+
+	t.Run("put and get object", func(t *testing.T) {
+		info, err := s.Put(ctx, "apod/2024-01-01.jpg", strings.NewReader("image"), "image/jpeg")
+		assert.NoError(t, err)
+		assert.Equal(t, "apod/2024-01-01.jpg", info.Key)
+		assert.Equal(t, int64(5), info.Size)
+		assert.Equal(t, "image/jpeg", info.ContentType)
+
+		object, stat, err := s.Get(ctx, "apod/2024-01-01.jpg")
+		assert.NoError(t, err)
+		defer object.Close()
+
+		content, err := io.ReadAll(object)
+		assert.NoError(t, err)
+		assert.Equal(t, "image", string(content))
+		assert.Equal(t, info.ETag, stat.ETag)
+	})
+
+	t.Run("list by prefix", func(t *testing.T) {
+		_, err := s.Put(ctx, "other/file.txt", strings.NewReader("text"), "")
+		assert.NoError(t, err)
+
+		objects, err := s.List(ctx, "apod/")
+		assert.NoError(t, err)
+		assert.Len(t, objects, 1)
+		assert.Equal(t, "apod/2024-01-01.jpg", objects[0].Key)
+	})
+
+	t.Run("keys cannot escape the root", func(t *testing.T) {
+		_, err := s.Put(ctx, "../../escape.txt", strings.NewReader("text"), "")
+		assert.NoError(t, err)
+
+		objects, err := s.List(ctx, "escape")
+		assert.NoError(t, err)
+		assert.Len(t, objects, 1)
+	})
+
+	t.Run("failed put keeps the previous object", func(t *testing.T) {
+		_, err := s.Put(ctx, "apod/2024-01-02.jpg", strings.NewReader("complete"), "image/jpeg")
+		assert.NoError(t, err)
+
+		_, err = s.Put(ctx, "apod/2024-01-02.jpg", io.MultiReader(strings.NewReader("trunc"), iotest.ErrReader(errors.New("connection reset"))), "image/jpeg")
+		assert.Error(t, err)
+
+		object, _, err := s.Get(ctx, "apod/2024-01-02.jpg")
+		assert.NoError(t, err)
+		defer object.Close()
+
+		content, err := io.ReadAll(object)
+		assert.NoError(t, err)
+		assert.Equal(t, "complete", string(content))
+
+		entries, err := os.ReadDir(filepath.Join(s.root, "apod"))
+		assert.NoError(t, err)
+		for _, entry := range entries {
+			assert.False(t, strings.HasPrefix(entry.Name(), tempFilePrefix), "temp file %s left behind", entry.Name())
+		}
+	})
+
+	t.Run("delete object", func(t *testing.T) {
+		assert.NoError(t, s.Delete(ctx, "apod/2024-01-01.jpg"))
+		assert.NoError(t, s.Delete(ctx, "apod/2024-01-01.jpg"))
+
+		_, err := s.Stat(ctx, "apod/2024-01-01.jpg")
+		assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+
+		_, _, err = s.Get(ctx, "apod/2024-01-01.jpg")
+		assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+	})
+}
@@ -0,0 +1,124 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/storage"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// putPartSize bounds the memory an upload takes. The body's size is unknown, so
+// the client buffers it one part at a time, and without a part size it picks
+// parts big enough for the largest object S3 allows, over 500 MiB each.
+const putPartSize = 16 << 20
+
+type S3Storage struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewS3Storage(ctx context.Context, cfg config.StorageConfig) (*S3Storage, error) {
+	client, err := minio.New(cfg.S3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.S3AccessKey.Value(), cfg.S3SecretKey.Value(), ""),
+		Secure: cfg.S3UseSSL,
+		Region: cfg.S3Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, cfg.S3Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check S3 bucket: %w", err)
+	}
+
+	if !exists {
+		if err := client.MakeBucket(ctx, cfg.S3Bucket, minio.MakeBucketOptions{Region: cfg.S3Region}); err != nil {
+			return nil, fmt.Errorf("failed to create S3 bucket: %w", err)
+		}
+	}
+
+	return &S3Storage{
+		client: client,
+		bucket: cfg.S3Bucket,
+	}, nil
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, contentType string) (storage.ObjectInfo, error) {
+	// With an unknown size the body is uploaded in parts of putPartSize, only
+	// one of them is held in memory at a time. Skipping the payload hash keeps
+	// the client from signing every part on the way.
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, -1, minio.PutObjectOptions{
+		ContentType:          contentType,
+		PartSize:             putPartSize,
+		DisableContentSha256: true,
+	})
+	if err != nil {
+		return storage.ObjectInfo{}, fmt.Errorf("failed to put object: %w", err)
+	}
+
+	return s.Stat(ctx, key)
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadSeekCloser, storage.ObjectInfo, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, storage.ObjectInfo{}, wrapNotFound(key, err)
+	}
+
+	stat, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, storage.ObjectInfo{}, wrapNotFound(key, err)
+	}
+
+	return object, objectInfo(stat), nil
+}
+
+func (s *S3Storage) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	stat, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return storage.ObjectInfo{}, wrapNotFound(key, err)
+	}
+
+	return objectInfo(stat), nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	if err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
+
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	var objects []storage.ObjectInfo
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", object.Err)
+		}
+		objects = append(objects, objectInfo(object))
+	}
+	return objects, nil
+}
+
+func objectInfo(object minio.ObjectInfo) storage.ObjectInfo {
+	return storage.ObjectInfo{
+		Key:          object.Key,
+		Size:         object.Size,
+		ContentType:  object.ContentType,
+		ETag:         fmt.Sprintf(`"%s"`, object.ETag),
+		LastModified: object.LastModified,
+	}
+}
+
+func wrapNotFound(key string, err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return fmt.Errorf("%w: %s", storage.ErrObjectNotFound, key)
+	}
+	return fmt.Errorf("failed to get object: %w", err)
+}
@@ -0,0 +1,80 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"nasa-apod-app/internal/config"
+	"nasa-apod-app/internal/storage"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS3Storage(t *testing.T) {
+	ctx := context.Background()
+
+	fake := httptest.NewServer(gofakes3.New(s3mem.New()).Server())
+	defer fake.Close()
+
+	s, err := NewS3Storage(ctx, config.StorageConfig{
+		S3Endpoint:  strings.TrimPrefix(fake.URL, "http://"),
+		S3Region:    "us-east-1",
+		S3Bucket:    "apod",
+		S3AccessKey: "access",
+		S3SecretKey: "secret",
+	})
+	assert.NoError(t, err)
+
+	t.Run("put and get object", func(t *testing.T) {
+		info, err := s.Put(ctx, "apod/2024-01-01.jpg", strings.NewReader("image"), "image/jpeg")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), info.Size)
+		assert.Equal(t, "image/jpeg", info.ContentType)
+
+		object, stat, err := s.Get(ctx, "apod/2024-01-01.jpg")
+		assert.NoError(t, err)
+		defer object.Close()
+
+		content, err := io.ReadAll(object)
+		assert.NoError(t, err)
+		assert.Equal(t, "image", string(content))
+		assert.Equal(t, info.ETag, stat.ETag)
+	})
+
+	t.Run("put buffers one bounded part", func(t *testing.T) {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+
+		_, err := s.Put(ctx, storage.ReservedPrefix+"readyz-probe", strings.NewReader("ok"), "text/plain")
+		assert.NoError(t, err)
+
+		runtime.ReadMemStats(&after)
+		assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(2*putPartSize), "a tiny upload must not allocate more than a part")
+		assert.NoError(t, s.Delete(ctx, storage.ReservedPrefix+"readyz-probe"))
+	})
+
+	t.Run("list by prefix", func(t *testing.T) {
+		_, err := s.Put(ctx, "other/file.txt", strings.NewReader("text"), "text/plain")
+		assert.NoError(t, err)
+
+		objects, err := s.List(ctx, "apod/")
+		assert.NoError(t, err)
+		assert.Len(t, objects, 1)
+		assert.Equal(t, "apod/2024-01-01.jpg", objects[0].Key)
+	})
+
+	t.Run("delete object", func(t *testing.T) {
+		assert.NoError(t, s.Delete(ctx, "apod/2024-01-01.jpg"))
+
+		_, err := s.Stat(ctx, "apod/2024-01-01.jpg")
+		assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+
+		_, _, err = s.Get(ctx, "apod/2024-01-01.jpg")
+		assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+	})
+}
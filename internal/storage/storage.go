@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+var ErrObjectNotFound = fmt.Errorf("object not found")
+
+// ReservedPrefix holds objects the service keeps for itself, such as the
+// readiness probe. They are not APOD images and are left out of usage metrics.
+const ReservedPrefix = "_internal/"
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// Storage is a blob store addressed by slash separated keys such as "apod/2024-01-01.jpg".
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (ObjectInfo, error)
+	Get(ctx context.Context, key string) (io.ReadSeekCloser, ObjectInfo, error)
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+}
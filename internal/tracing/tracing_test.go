@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"nasa-apod-app/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInstrument(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider, err := NewTracerProvider(context.Background(), config.TracingConfig{ServiceName: "apod-test"}, sdktrace.WithSyncer(exporter))
+	assert.NoError(t, err)
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	var handlerSpan trace.SpanContext
+	router := mux.NewRouter()
+	router.HandleFunc("/api/apod/{date}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+	}).Methods(http.MethodGet)
+	router.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet)
+	handler := Instrument(router, router)
+
+	t.Run("names spans after the route and continues the caller's trace", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/apod/2024-01-01", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := exporter.GetSpans()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, "GET /api/apod/{date}", spans[0].Name)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+		assert.Equal(t, spans[0].SpanContext.SpanID(), handlerSpan.SpanID())
+		serviceName, _ := spans[0].Resource.Set().Value("service.name")
+		assert.Equal(t, "apod-test", serviceName.AsString())
+	})
+
+	t.Run("skips probes", func(t *testing.T) {
+		exporter.Reset()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		assert.Empty(t, exporter.GetSpans())
+	})
+}
+
+func TestInitRejectsUnknownExporter(t *testing.T) {
+	_, err := Init(context.Background(), config.TracingConfig{Exporter: "zipkin"})
+	assert.Error(t, err)
+}